@@ -0,0 +1,172 @@
+package prompt
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Pre-tokenization patterns of the OpenAI cl100k_base and o200k_base encodings.
+// The trailing `\s+(?!\S)|\s+` alternatives use a lookahead that Go's regexp
+// does not support, so BPETokenizer implements them by hand.
+const (
+	PatternCL100K = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+`
+	PatternO200K  = `[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?` +
+		`|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n/]*|\s*[\r\n]+`
+)
+
+// BPETokenizer is an offline byte pair encoding tokenizer that reads
+// tiktoken-style merge tables, as used by the cl100k_base and o200k_base
+// encodings. Special tokens are not supported.
+type BPETokenizer struct {
+	ranks   map[string]int
+	pattern *regexp.Regexp
+}
+
+// NewBPETokenizer reads a merge table in the tiktoken format (one
+// "<base64 token> <rank>" pair per line) and splits text with the given
+// pre-tokenization pattern, usually PatternCL100K or PatternO200K.
+func NewBPETokenizer(r io.Reader, pattern string) (*BPETokenizer, error) {
+	re, err := regexp.Compile(`^(?:` + pattern + `)`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pre-tokenization pattern: %w", err)
+	}
+
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var line int
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("merge table line %d: expected \"<token> <rank>\"", line)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("merge table line %d: invalid base64 token: %w", line, err)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("merge table line %d: invalid rank: %w", line, err)
+		}
+		ranks[string(decoded)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read merge table: %w", err)
+	}
+
+	// Every byte must be encodable on its own, otherwise Encode could get stuck
+	for b := 0; b < 256; b++ {
+		if _, ok := ranks[string([]byte{byte(b)})]; !ok {
+			return nil, fmt.Errorf("merge table has no token for byte 0x%02x", b)
+		}
+	}
+
+	return &BPETokenizer{ranks: ranks, pattern: re}, nil
+}
+
+// LoadBPEFile loads a tiktoken merge table such as cl100k_base.tiktoken
+func LoadBPEFile(path string, pattern string) (*BPETokenizer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open merge table: %w", err)
+	}
+	defer f.Close()
+
+	return NewBPETokenizer(f, pattern)
+}
+
+// Encode returns the token ids of text
+func (t *BPETokenizer) Encode(text string) []int {
+	var tokens []int
+	t.split(text, func(piece string) {
+		if rank, ok := t.ranks[piece]; ok {
+			tokens = append(tokens, rank)
+			return
+		}
+		tokens = append(tokens, t.merge(piece)...)
+	})
+	return tokens
+}
+
+func (t *BPETokenizer) CountTokens(text string) int {
+	return len(t.Encode(text))
+}
+
+// split cuts text into pre-tokenization pieces
+func (t *BPETokenizer) split(text string, fn func(piece string)) {
+	for pos := 0; pos < len(text); {
+		if loc := t.pattern.FindStringIndex(text[pos:]); loc != nil && loc[1] > 0 {
+			fn(text[pos : pos+loc[1]])
+			pos += loc[1]
+			continue
+		}
+
+		// `\s+(?!\S)|\s+`: a whitespace run leaves its last character to the
+		// following word unless it reaches the end of the text
+		end, last := pos, pos
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			last = end
+			end += size
+		}
+		switch {
+		case end == pos:
+			// Not matched by the pattern and not whitespace: emit a single rune
+			_, size := utf8.DecodeRuneInString(text[pos:])
+			end = pos + size
+		case end < len(text) && last > pos:
+			end = last
+		}
+		fn(text[pos:end])
+		pos = end
+	}
+}
+
+// merge applies byte pair merges to piece, always joining the adjacent pair
+// with the lowest rank first
+func (t *BPETokenizer) merge(piece string) []int {
+	parts := make([]string, len(piece))
+	for i := 0; i < len(piece); i++ {
+		parts[i] = piece[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		best, bestRank := -1, 0
+		for i := 0; i < len(parts)-1; i++ {
+			rank, ok := t.ranks[parts[i]+parts[i+1]]
+			if ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	tokens := make([]int, len(parts))
+	for i, part := range parts {
+		tokens[i] = t.ranks[part]
+	}
+	return tokens
+}
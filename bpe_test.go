@@ -0,0 +1,154 @@
+package prompt
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTestMergeTable writes a tiny tiktoken-style table: all single bytes
+// followed by a handful of merges
+func writeTestMergeTable(t *testing.T) string {
+	t.Helper()
+
+	var b strings.Builder
+	b.WriteString(byteOnlyTable())
+	for i, token := range []string{"he", "ll", "hell", "hello", " w", "é"} {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), 256+i)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.tiktoken")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBPETokenizerEncode(t *testing.T) {
+	tok, err := LoadBPEFile(writeTestMergeTable(t), PatternCL100K)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		input    string
+		expected []int
+	}{
+		{input: "hello", expected: []int{259}},
+		{input: "hello world", expected: []int{259, 260, 'o', 'r', 'l', 'd'}},
+		{input: "hell", expected: []int{258}},
+		{input: "shell", expected: []int{'s', 258}},
+		{input: "helium", expected: []int{256, 'l', 'i', 'u', 'm'}},
+		{input: "café", expected: []int{'c', 'a', 'f', 261}},
+		{input: "日本", expected: []int{0xe6, 0x97, 0xa5, 0xe6, 0x9c, 0xac}},
+		{input: "", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			actual := tok.Encode(tt.input)
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, actual)
+			}
+			if tok.CountTokens(tt.input) != len(tt.expected) {
+				t.Errorf("Expected %d tokens, got %d", len(tt.expected), tok.CountTokens(tt.input))
+			}
+		})
+	}
+}
+
+func TestBPETokenizerSplit(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		input    string
+		expected []string
+	}{
+		{PatternCL100K, "Hello world", []string{"Hello", " world"}},
+		{PatternCL100K, "a  b\n", []string{"a", " ", " b", "\n"}},
+		{PatternCL100K, "trailing   ", []string{"trailing", "   "}},
+		{PatternCL100K, "12345 it's", []string{"123", "45", " it", "'s"}},
+		{PatternCL100K, `{"id": 1}`, []string{`{"`, "id", `":`, " ", "1", "}"}},
+		{PatternO200K, "HelloWorld", []string{"Hello", "World"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			tok, err := NewBPETokenizer(strings.NewReader(byteOnlyTable()), tt.pattern)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var pieces []string
+			tok.split(tt.input, func(piece string) { pieces = append(pieces, piece) })
+			if !reflect.DeepEqual(pieces, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, pieces)
+			}
+		})
+	}
+}
+
+func TestNewBPETokenizerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   string
+		pattern string
+	}{
+		{name: "missing bytes", table: "YQ== 0\n", pattern: PatternCL100K},
+		{name: "invalid base64", table: "!!! 0\n", pattern: PatternCL100K},
+		{name: "invalid rank", table: "YQ== x\n", pattern: PatternCL100K},
+		{name: "missing rank", table: "YQ==\n", pattern: PatternCL100K},
+		{name: "invalid pattern", table: byteOnlyTable(), pattern: "("},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBPETokenizer(strings.NewReader(tt.table), tt.pattern); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+
+	if _, err := LoadBPEFile(filepath.Join(t.TempDir(), "missing"), PatternCL100K); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestPromptTokenCountWithTokenizer(t *testing.T) {
+	tok, err := LoadBPEFile(writeTestMergeTable(t), PatternCL100K)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	p := NewPrompt()
+	p.AddSection(Section{Intro: "hello", Instructions: []Instruction{"hello"}})
+
+	heuristic := p.TokenCount()
+
+	p.SetTokenizer(tok)
+	if expected := tok.CountTokens(p.String()); p.TokenCount() != expected {
+		t.Errorf("Expected %d tokens, got %d", expected, p.TokenCount())
+	}
+
+	p.SetTokenizer(nil)
+	if p.TokenCount() != heuristic {
+		t.Errorf("Expected heuristic count %d after reset, got %d", heuristic, p.TokenCount())
+	}
+}
+
+func TestHeuristicTokenizer(t *testing.T) {
+	if n := (HeuristicTokenizer{}).CountTokens("one two three four five"); n != 7 {
+		t.Errorf("Expected 7 tokens, got %d", n)
+	}
+}
+
+func byteOnlyTable() string {
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	return b.String()
+}
@@ -0,0 +1,233 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"unicode/utf8"
+)
+
+// FitPolicy is a strategy FitTo uses to shrink a prompt
+type FitPolicy int
+
+const (
+	// DropLowestPriority removes whole sections, lowest Priority first. Ties
+	// are broken by dropping the section rendered last. Required sections are
+	// never dropped.
+	DropLowestPriority FitPolicy = iota
+	// TruncateLongestDataBlock cuts the longest data block and appends an
+	// elision marker. Data blocks of Required sections are never cut, nor are
+	// blocks so short that the marker would not save any tokens.
+	TruncateLongestDataBlock
+	// DropTrailingInstructions removes instructions from the end of the
+	// rendered prompt, skipping Required sections
+	DropTrailingInstructions
+)
+
+func (fp FitPolicy) String() string {
+	switch fp {
+	case DropLowestPriority:
+		return "drop lowest priority section"
+	case TruncateLongestDataBlock:
+		return "truncate longest data block"
+	case DropTrailingInstructions:
+		return "drop trailing instruction"
+	}
+	return fmt.Sprintf("FitPolicy(%d)", int(fp))
+}
+
+// ErrBudgetExceeded is returned by FitTo when the policies could not shrink
+// the prompt far enough
+var ErrBudgetExceeded = errors.New("prompt exceeds token budget")
+
+// elisionMarker is appended to truncated data blocks
+const elisionMarker = "\n[... %d bytes truncated ...]"
+
+// Removal describes one change FitTo made to the prompt
+type Removal struct {
+	Policy FitPolicy
+	Intro  string // intro of the affected section
+	Detail string // what was removed or truncated
+	Tokens int    // tokens saved by this change
+}
+
+// FitReport summarizes the changes FitTo made
+type FitReport struct {
+	Before  int // token count before fitting
+	After   int // token count after fitting
+	Removed []Removal
+}
+
+// FitTo shrinks the prompt in place until TokenCount is at most maxTokens.
+// Policies are applied in the given order, each until the prompt fits or the
+// policy has nothing left to remove. The report lists every change; if the
+// prompt still does not fit, the error wraps ErrBudgetExceeded.
+func (p *Prompt) FitTo(maxTokens int, policies ...FitPolicy) (FitReport, error) {
+	report := FitReport{Before: p.TokenCount()}
+	tokens := report.Before
+
+	for _, policy := range policies {
+		for tokens > maxTokens {
+			removal, ok := p.shrink(policy, maxTokens)
+			if !ok {
+				break
+			}
+			after := p.TokenCount()
+			removal.Tokens = tokens - after
+			tokens = after
+			report.Removed = append(report.Removed, removal)
+		}
+	}
+
+	report.After = tokens
+	if tokens > maxTokens {
+		return report, fmt.Errorf("%w: %d tokens, budget %d", ErrBudgetExceeded, tokens, maxTokens)
+	}
+	return report, nil
+}
+
+// shrink applies one step of policy and reports whether anything changed
+func (p *Prompt) shrink(policy FitPolicy, maxTokens int) (Removal, bool) {
+	switch policy {
+	case DropLowestPriority:
+		return p.dropLowestPrioritySection()
+	case TruncateLongestDataBlock:
+		return p.truncateLongestDataBlock(maxTokens)
+	case DropTrailingInstructions:
+		return p.dropTrailingInstruction()
+	}
+	return Removal{}, false
+}
+
+func (p *Prompt) dropLowestPrioritySection() (Removal, bool) {
+	drop := -1
+	for _, i := range renderIndexes(p.Sections) {
+		section := p.Sections[i]
+		if section.Required {
+			continue
+		}
+		if drop < 0 || section.Priority <= p.Sections[drop].Priority {
+			drop = i
+		}
+	}
+	if drop < 0 {
+		return Removal{}, false
+	}
+
+	removed := p.Sections[drop]
+	p.Sections = append(p.Sections[:drop:drop], p.Sections[drop+1:]...)
+
+	return Removal{
+		Policy: DropLowestPriority,
+		Intro:  removed.Intro,
+		Detail: fmt.Sprintf("section with priority %d, %d instructions and %d data blocks",
+			removed.Priority, len(removed.Instructions), len(removed.DataBlocks)),
+	}, true
+}
+
+func (p *Prompt) dropTrailingInstruction() (Removal, bool) {
+	order := renderIndexes(p.Sections)
+	for k := len(order) - 1; k >= 0; k-- {
+		section := &p.Sections[order[k]]
+		if section.Required || len(section.Instructions) == 0 {
+			continue
+		}
+
+		last := len(section.Instructions) - 1
+		removed := section.Instructions[last]
+		section.Instructions = section.Instructions[:last:last]
+
+		return Removal{
+			Policy: DropTrailingInstructions,
+			Intro:  section.Intro,
+			Detail: "instruction: " + string(removed),
+		}, true
+	}
+	return Removal{}, false
+}
+
+// truncateLongestDataBlock keeps the longest prefix of the longest data block
+// outside Required sections that fits the budget. A block that cannot fit at
+// all is cut down to the elision marker so the next call moves on to the next
+// longest block. Blocks too short to shrink this way, because the marker
+// costs as many tokens as the content, are left alone.
+func (p *Prompt) truncateLongestDataBlock(maxTokens int) (Removal, bool) {
+	type candidate struct{ section, block, length int }
+	var candidates []candidate
+	for i, section := range p.Sections {
+		if section.Required {
+			continue
+		}
+		for j, block := range section.DataBlocks {
+			if len(block.Content) > 0 && !isElided(block.Content) {
+				candidates = append(candidates, candidate{i, j, len(block.Content)})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].length > candidates[b].length })
+
+	before := p.TokenCount()
+	for _, c := range candidates {
+		if removal, ok := p.truncateDataBlock(c.section, c.block, maxTokens, before); ok {
+			return removal, true
+		}
+	}
+	return Removal{}, false
+}
+
+// truncateDataBlock truncates one data block as truncateLongestDataBlock
+// describes. If that does not bring TokenCount below before, the block is
+// restored and ok is false.
+func (p *Prompt) truncateDataBlock(si, bi, maxTokens, before int) (Removal, bool) {
+	section := &p.Sections[si]
+	blocks := section.DataBlocks
+	// copy the blocks so truncation does not leak into slices shared with the caller
+	section.DataBlocks = append([]DataBlock(nil), blocks...)
+	block := &section.DataBlocks[bi]
+	original := block.Content
+
+	// truncate keeps the first n bytes, backing off to a rune boundary
+	truncate := func(n int) (string, int) {
+		for n > 0 && !utf8.RuneStart(original[n]) {
+			n--
+		}
+		return original[:n] + fmt.Sprintf(elisionMarker, len(original)-n), len(original) - n
+	}
+
+	// binary search for the longest prefix that still fits
+	lo, hi := 0, len(original)-1
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		block.Content, _ = truncate(mid)
+		if p.TokenCount() <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	var cut int
+	block.Content, cut = truncate(lo)
+	if p.TokenCount() >= before {
+		section.DataBlocks = blocks
+		return Removal{}, false
+	}
+
+	label := block.Label
+	if label == "" {
+		label = block.Type
+	}
+	return Removal{
+		Policy: TruncateLongestDataBlock,
+		Intro:  section.Intro,
+		Detail: fmt.Sprintf("data block %q: truncated %d of %d bytes", label, cut, len(original)),
+	}, true
+}
+
+var elidedPattern = regexp.MustCompile(`\n\[\.\.\. \d+ bytes truncated \.\.\.\]$`)
+
+// isElided reports whether content was already truncated by FitTo
+func isElided(content string) bool {
+	return elidedPattern.MatchString(content)
+}
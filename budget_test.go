@@ -0,0 +1,218 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFitToAlreadyFits(t *testing.T) {
+	p := newTestPrompt()
+	before := p.String()
+
+	report, err := p.FitTo(p.TokenCount(), DropLowestPriority)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(report.Removed) != 0 || p.String() != before {
+		t.Errorf("Expected no changes, got %+v", report.Removed)
+	}
+}
+
+func TestFitToDropLowestPriority(t *testing.T) {
+	p := newTestPrompt()
+	budget := p.TokenCount() - 1
+
+	report, err := p.FitTo(budget, DropLowestPriority)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(p.Sections) != 1 || p.Sections[0].Intro != "Task" {
+		t.Errorf("Expected the last section to be dropped, got %d sections", len(p.Sections))
+	}
+	if len(report.Removed) != 1 || report.Removed[0].Intro != "Style:" || report.Removed[0].Policy != DropLowestPriority {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if report.After != p.TokenCount() || report.After > budget || report.Before-report.After != report.Removed[0].Tokens {
+		t.Errorf("Inconsistent token numbers in report %+v", report)
+	}
+}
+
+func TestFitToTruncateLongestDataBlock(t *testing.T) {
+	docs := NewSection("Documents")
+	docs.AddRawJSON("Doc 1", strings.Repeat("lorem ipsum ", 200))
+	docs.AddRawJSON("Doc 2", strings.Repeat("dolor ", 20))
+
+	p := NewPrompt()
+	p.AddSection(docs)
+	original := docs.DataBlocks[0].Content
+
+	report, err := p.FitTo(200, TruncateLongestDataBlock)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.TokenCount() > 200 {
+		t.Errorf("Expected at most 200 tokens, got %d", p.TokenCount())
+	}
+
+	block := p.Sections[0].DataBlocks[0]
+	if !strings.HasPrefix(original, strings.SplitN(block.Content, "\n", 2)[0]) {
+		t.Errorf("Expected truncated content to be a prefix of the original")
+	}
+	if !strings.Contains(block.Content, "bytes truncated ...]") {
+		t.Errorf("Expected elision marker, got %q", block.Content)
+	}
+	if p.Sections[0].DataBlocks[1].Content != strings.Repeat("dolor ", 20) {
+		t.Error("Expected the shorter block to stay untouched")
+	}
+	if len(report.Removed) != 1 || !strings.Contains(report.Removed[0].Detail, `"Doc 1"`) {
+		t.Errorf("Unexpected report %+v", report.Removed)
+	}
+}
+
+func TestFitToDoesNotModifyCallerDataBlocks(t *testing.T) {
+	section := NewSection("Documents")
+	section.AddRawJSON("Doc", strings.Repeat("word ", 100))
+
+	p := NewPrompt()
+	p.AddSection(section)
+
+	if _, err := p.FitTo(20, TruncateLongestDataBlock); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if section.DataBlocks[0].Content != strings.Repeat("word ", 100) {
+		t.Error("Expected the caller's section to stay untouched")
+	}
+}
+
+func TestFitToDropTrailingInstructions(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{Intro: "Task", Instructions: []Instruction{"one two three", "four five six"}})
+	p.AddSection(Section{Intro: "Style", Instructions: []Instruction{"seven eight nine"}})
+
+	report, err := p.FitTo(p.TokenCount()-7, DropTrailingInstructions)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(p.Sections[1].Instructions) != 0 || len(p.Sections[0].Instructions) != 1 {
+		t.Errorf("Expected the last two instructions to be removed, got %+v", p.Sections)
+	}
+	if len(report.Removed) != 2 || report.Removed[0].Detail != "instruction: seven eight nine" {
+		t.Errorf("Unexpected report %+v", report.Removed)
+	}
+}
+
+func TestFitToPolicyOrder(t *testing.T) {
+	docs := NewSection("Documents")
+	docs.AddRawJSON("Doc", strings.Repeat("lorem ipsum ", 200))
+
+	p := NewPrompt()
+	p.AddSection(docs)
+	p.AddSection(Section{Intro: "Examples", Instructions: []Instruction{"Q: What is Go? A: A programming language"}})
+
+	report, err := p.FitTo(100, TruncateLongestDataBlock, DropLowestPriority)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.Removed[0].Policy != TruncateLongestDataBlock {
+		t.Errorf("Expected truncation to run first, got %+v", report.Removed)
+	}
+	if p.TokenCount() > 100 {
+		t.Errorf("Expected at most 100 tokens, got %d", p.TokenCount())
+	}
+}
+
+func TestFitToBudgetExceeded(t *testing.T) {
+	p := newTestPrompt()
+
+	report, err := p.FitTo(10, DropTrailingInstructions)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+	if report.After != p.TokenCount() || report.After <= 10 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestFitToRespectsPriorityAndRequired(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{Intro: "Task definition", Instructions: []Instruction{"Classify the ticket"}, Required: true})
+	p.AddSection(Section{Intro: "Few-shot examples", Instructions: []Instruction{"Example one two three"}, Priority: -1})
+	p.AddSection(Section{Intro: "Background", Instructions: []Instruction{"Some background text"}, Priority: 5})
+	p.AddSection(Section{Intro: "Output format", Instructions: []Instruction{"Answer with JSON"}, Required: true, PinToEnd: true})
+
+	report, err := p.FitTo(0, DropLowestPriority)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+
+	var dropped []string
+	for _, r := range report.Removed {
+		dropped = append(dropped, r.Intro)
+	}
+	if strings.Join(dropped, ",") != "Few-shot examples,Background" {
+		t.Errorf("Expected examples to be dropped before background, got %v", dropped)
+	}
+	if len(p.Sections) != 2 || !p.Sections[0].Required || !p.Sections[1].Required {
+		t.Errorf("Expected only the required sections to remain, got %+v", p.Sections)
+	}
+}
+
+func TestFitToDropTrailingInstructionsSkipsRequired(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{Intro: "Output format", Instructions: []Instruction{"Answer with JSON"}, Required: true, PinToEnd: true})
+	p.AddSection(Section{Intro: "Hints", Instructions: []Instruction{"hint one", "hint two"}})
+
+	report, err := p.FitTo(0, DropTrailingInstructions)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+	if len(report.Removed) != 2 || report.Removed[0].Detail != "instruction: hint two" {
+		t.Errorf("Unexpected report %+v", report.Removed)
+	}
+	if len(p.Sections[0].Instructions) != 1 {
+		t.Error("Expected required section to keep its instructions")
+	}
+}
+
+func TestFitToTruncateLongestDataBlockSkipsRequired(t *testing.T) {
+	schema := NewSection("Output schema")
+	schema.Required = true
+	schema.AddRawJSON("Schema", strings.Repeat("field ", 100))
+
+	docs := NewSection("Documents")
+	docs.AddRawJSON("Doc", strings.Repeat("word ", 50))
+
+	p := NewPrompt()
+	p.AddSection(schema)
+	p.AddSection(docs)
+
+	report, err := p.FitTo(0, TruncateLongestDataBlock)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+	if p.Sections[0].DataBlocks[0].Content != strings.Repeat("field ", 100) {
+		t.Error("Expected required section to keep its data block")
+	}
+	if len(report.Removed) != 1 || report.Removed[0].Intro != "Documents" {
+		t.Errorf("Unexpected report %+v", report.Removed)
+	}
+}
+
+func TestFitToTruncateLongestDataBlockNeverGrows(t *testing.T) {
+	p := NewPrompt()
+	section := NewSection("Task")
+	section.AddRawJSON("Doc", "{}")
+	p.AddSection(section)
+
+	report, err := p.FitTo(5, TruncateLongestDataBlock)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+	if len(report.Removed) != 0 {
+		t.Errorf("Expected no removals, got %+v", report.Removed)
+	}
+	if p.Sections[0].DataBlocks[0].Content != "{}" || report.After != report.Before {
+		t.Errorf("Expected the block to be kept, got %q and report %+v", p.Sections[0].DataBlocks[0].Content, report)
+	}
+}
@@ -0,0 +1,180 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Builder constructs a prompt with chained calls:
+//
+//	p, err := prompt.Build().
+//		Section("Task").
+//		Instruct("Summarize the input").
+//		JSON("Input", data).
+//		Meta(prompt.ModelKey, "gpt-4").
+//		Done()
+//
+// Instructions, data blocks and section options apply to the section started
+// by the last Section call. Errors are collected and returned by Done, so the
+// chain never has to be interrupted. A Builder builds one prompt: after Done it
+// hands the prompt over and every further call fails with ErrBuilderDone.
+type Builder struct {
+	p       *Prompt // nil after Done
+	current int     // index of the section being built, -1 before the first Section
+	errs    []error
+}
+
+// ErrBuilderDone is returned by Done for calls made on a Builder after Done
+var ErrBuilderDone = errors.New("builder already done")
+
+// Build starts a new prompt
+func Build() *Builder {
+	return &Builder{p: NewPrompt(), current: -1}
+}
+
+// Section starts a new section with the given intro
+func (b *Builder) Section(intro string) *Builder {
+	if !b.live("Section") {
+		return b
+	}
+	b.p.AddSection(NewSection(intro))
+	b.current = len(b.p.Sections) - 1
+	return b
+}
+
+// Instruct adds an instruction to the current section. The text is cleaned up
+// by NewInstruction.
+func (b *Builder) Instruct(instruction string) *Builder {
+	if s := b.section("Instruct"); s != nil {
+		s.AddInstruction(NewInstruction(instruction))
+	}
+	return b
+}
+
+// JSON marshals data and adds it as a JSON data block
+func (b *Builder) JSON(label string, data any) *Builder {
+	if s := b.section("JSON"); s != nil {
+		b.check(s, s.AddJSONData(label, data))
+	}
+	return b
+}
+
+// RawJSON adds pre-formatted JSON as a data block
+func (b *Builder) RawJSON(label string, jsonString string) *Builder {
+	if s := b.section("RawJSON"); s != nil {
+		s.AddRawJSON(label, jsonString)
+	}
+	return b
+}
+
+// XML marshals data and adds it as an XML data block
+func (b *Builder) XML(label string, data any) *Builder {
+	if s := b.section("XML"); s != nil {
+		b.check(s, s.AddXMLData(label, data))
+	}
+	return b
+}
+
+// RawXML adds pre-formatted XML as a data block
+func (b *Builder) RawXML(label string, xmlString string) *Builder {
+	if s := b.section("RawXML"); s != nil {
+		s.AddRawXML(label, xmlString)
+	}
+	return b
+}
+
+// Table adds rows as a tabular data block, see Section.AddTable
+func (b *Builder) Table(label string, rows any, opts TableOptions) *Builder {
+	if s := b.section("Table"); s != nil {
+		b.check(s, s.AddTable(label, rows, opts))
+	}
+	return b
+}
+
+// HTML adds pre-formatted HTML as a data block
+func (b *Builder) HTML(label string, htmlString string) *Builder {
+	if s := b.section("HTML"); s != nil {
+		s.AddRawHTML(label, htmlString)
+	}
+	return b
+}
+
+// Priority sets the priority of the current section
+func (b *Builder) Priority(priority int) *Builder {
+	if s := b.section("Priority"); s != nil {
+		s.Priority = priority
+	}
+	return b
+}
+
+// Required marks the current section as required
+func (b *Builder) Required() *Builder {
+	if s := b.section("Required"); s != nil {
+		s.Required = true
+	}
+	return b
+}
+
+// PinToEnd renders the current section after all unpinned sections
+func (b *Builder) PinToEnd() *Builder {
+	if s := b.section("PinToEnd"); s != nil {
+		s.PinToEnd = true
+	}
+	return b
+}
+
+// Meta sets a metadata value
+func (b *Builder) Meta(key string, value any) *Builder {
+	if b.live("Meta") {
+		b.p.SetMetadata(key, value)
+	}
+	return b
+}
+
+// Tokenizer sets the tokenizer used by TokenCount
+func (b *Builder) Tokenizer(t Tokenizer) *Builder {
+	if b.live("Tokenizer") {
+		b.p.SetTokenizer(t)
+	}
+	return b
+}
+
+// Done returns the built prompt, or all errors collected along the way. The
+// builder gives up the prompt, so later calls cannot change it.
+func (b *Builder) Done() (*Prompt, error) {
+	if !b.live("Done") || len(b.errs) > 0 {
+		return nil, errors.Join(b.errs...)
+	}
+	p := b.p
+	b.p = nil
+	return p, nil
+}
+
+// live reports whether the builder still holds its prompt, recording an error
+// for call otherwise
+func (b *Builder) live(call string) bool {
+	if b.p == nil {
+		b.errs = append(b.errs, fmt.Errorf("%s: %w", call, ErrBuilderDone))
+		return false
+	}
+	return true
+}
+
+// section returns the section being built, or records an error when call was
+// made after Done or before the first Section
+func (b *Builder) section(call string) *Section {
+	if !b.live(call) {
+		return nil
+	}
+	if b.current < 0 {
+		b.errs = append(b.errs, fmt.Errorf("%s called before Section", call))
+		return nil
+	}
+	return &b.p.Sections[b.current]
+}
+
+func (b *Builder) check(s *Section, err error) {
+	if err != nil {
+		b.errs = append(b.errs, fmt.Errorf("section %q: %w", s.Intro, err))
+	}
+}
@@ -0,0 +1,89 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	p, err := Build().
+		Section("Task").
+		Instruct("  Summarize the input  ").
+		JSON("Input", map[string]int{"id": 1}).
+		Section("Output").
+		Instruct("Respond in JSON").
+		Required().
+		PinToEnd().
+		Section("Examples").
+		Priority(-1).
+		RawXML("", "<a/>").
+		Meta(ModelKey, "gpt-4").
+		Done()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "\nTask:\n- Summarize the input\n\nInput:\n```json\n{\n  \"id\": 1\n}\n```\n---" +
+		"\nExamples:\n```xml\n<a/>\n```\n---" +
+		"\nOutput:\n- Respond in JSON\n---"
+	if p.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, p.String())
+	}
+	if p.Sections[2].Priority != -1 || !p.Sections[1].Required {
+		t.Errorf("Expected section options to be set, got %+v", p.Sections)
+	}
+	if got := p.GetMetadataString(ModelKey); got != "gpt-4" {
+		t.Errorf("Expected model 'gpt-4', got %q", got)
+	}
+}
+
+func TestBuilderCollectsErrors(t *testing.T) {
+	p, err := Build().
+		Instruct("too early").
+		Section("Task").
+		JSON("Bad", make(chan int)).
+		XML("Also bad", map[string]int{"a": 1}).
+		Instruct("still fine").
+		Done()
+
+	if p != nil {
+		t.Errorf("Expected nil prompt, got %v", p)
+	}
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+
+	for _, want := range []string{
+		"Instruct called before Section",
+		`section "Task": failed to marshal data to JSON`,
+		`section "Task": failed to marshal data to XML`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %q", want, err.Error())
+		}
+	}
+}
+
+func TestBuilderDoneHandsOverPrompt(t *testing.T) {
+	b := Build().Section("Task").Instruct("Say hello")
+
+	p, err := b.Done()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := p.String()
+
+	b.Instruct("Say goodbye").Section("Extra").Meta(ModelKey, "gpt-4")
+	if p.String() != expected || p.GetMetadataString(ModelKey) != "" {
+		t.Errorf("Expected calls after Done not to change the prompt, got %q", p.String())
+	}
+
+	again, err := b.Done()
+	if again != nil || !errors.Is(err, ErrBuilderDone) {
+		t.Errorf("Expected ErrBuilderDone, got %v, %v", again, err)
+	}
+	if !strings.Contains(err.Error(), "Instruct: builder already done") {
+		t.Errorf("Expected error to name the call, got %q", err.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package prompt
+
+// Clone returns a deep copy of the prompt. Sections, instructions, data
+// blocks and metadata are copied, so changes to the clone never affect p and
+// vice versa. Nested metadata maps and slices (map[string]any, []any) are
+// copied as well; other metadata values and the tokenizer are shared.
+//
+// Text is not duplicated, so cloning a prompt with large data blocks is cheap.
+func (p *Prompt) Clone() *Prompt {
+	c := &Prompt{
+		metadata:  make(map[string]any, len(p.metadata)),
+		tokenizer: p.tokenizer,
+	}
+	for k, v := range p.metadata {
+		c.metadata[k] = cloneValue(v)
+	}
+	if p.Sections != nil {
+		c.Sections = make([]Section, len(p.Sections))
+		for i, section := range p.Sections {
+			c.Sections[i] = section.Clone()
+		}
+	}
+	return c
+}
+
+// Clone returns a copy of the section with its own instruction and data block
+// slices, so appending to the copy never writes into the original
+func (s Section) Clone() Section {
+	if s.Instructions != nil {
+		s.Instructions = append(make([]Instruction, 0, len(s.Instructions)), s.Instructions...)
+	}
+	if s.DataBlocks != nil {
+		s.DataBlocks = append(make([]DataBlock, 0, len(s.DataBlocks)), s.DataBlocks...)
+	}
+	return s
+}
+
+// cloneValue copies the generic maps and slices produced by decoding JSON,
+// YAML or TOML metadata
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		c := make(map[string]any, len(v))
+		for k, e := range v {
+			c[k] = cloneValue(e)
+		}
+		return c
+	case []any:
+		c := make([]any, len(v))
+		for i, e := range v {
+			c[i] = cloneValue(e)
+		}
+		return c
+	}
+	return v
+}
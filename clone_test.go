@@ -0,0 +1,83 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPromptClone(t *testing.T) {
+	// spare capacity makes appends write into the shared backing array
+	section := Section{Intro: "Task", Instructions: make([]Instruction, 0, 8), DataBlocks: make([]DataBlock, 0, 8)}
+	section.AddInstruction("Summarize")
+	section.AddRawJSON("Input", `{"id": 1}`)
+
+	base := NewPrompt()
+	base.Sections = make([]Section, 0, 8)
+	base.AddSection(section)
+	base.SetMetadata(ModelKey, "gpt-4")
+	base.SetMetadata("tags", []any{"a", map[string]any{"b": 1}})
+	base.SetTokenizer(HeuristicTokenizer{})
+	want := base.String()
+
+	c := base.Clone()
+	if c.String() != want {
+		t.Errorf("Expected clone to render %q, got %q", want, c.String())
+	}
+	if !reflect.DeepEqual(c.GetAllMetadata(), base.GetAllMetadata()) {
+		t.Errorf("Expected metadata %v, got %v", base.GetAllMetadata(), c.GetAllMetadata())
+	}
+	if c.tokenizer != base.tokenizer {
+		t.Error("Expected clone to keep the tokenizer")
+	}
+
+	c.AddSection(NewSection("Extra"))
+	c.Sections[0].AddInstruction("Be brief")
+	c.Sections[0].AddRawXML("More", "<a/>")
+	c.Sections[0].Instructions[0] = "Translate"
+	c.Sections[0].DataBlocks[0].Content = "{}"
+	c.Sections[0].Intro = "Changed"
+	c.SetMetadata(ModelKey, "claude")
+	c.GetAllMetadata()["tags"].([]any)[1].(map[string]any)["b"] = 2
+
+	if base.String() != want {
+		t.Errorf("Expected base to stay %q, got %q", want, base.String())
+	}
+	if got := base.GetMetadataString(ModelKey); got != "gpt-4" {
+		t.Errorf("Expected base model 'gpt-4', got %q", got)
+	}
+	tags, _ := base.GetMetadata("tags")
+	if b := tags.([]any)[1].(map[string]any)["b"]; b != 1 {
+		t.Errorf("Expected nested metadata to stay 1, got %v", b)
+	}
+}
+
+func TestPromptCloneEmpty(t *testing.T) {
+	c := (&Prompt{}).Clone()
+
+	if c.Sections != nil {
+		t.Errorf("Expected nil sections, got %v", c.Sections)
+	}
+	c.SetMetadata("key", "value")
+	if !c.HasMetadata("key") {
+		t.Error("Expected clone metadata to be writable")
+	}
+}
+
+func TestSectionClone(t *testing.T) {
+	// spare capacity makes appends write into the shared backing array
+	base := Section{Intro: "Task", Instructions: make([]Instruction, 0, 8), Priority: 2}
+	base.AddInstruction("Summarize")
+	want := base.String()
+
+	c := base.Clone()
+	c.AddInstruction("Be brief")
+	c.AddRawHTML("Page", "<p>hi</p>")
+	c.Instructions[0] = "Translate"
+
+	if base.String() != want {
+		t.Errorf("Expected base section to stay %q, got %q", want, base.String())
+	}
+	if c.Priority != base.Priority {
+		t.Errorf("Expected priority %d, got %d", base.Priority, c.Priority)
+	}
+}
@@ -0,0 +1,176 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ModelHints suggest what kind of model should handle the prompt
+type ModelHints struct {
+	HighQuality bool // stored as "model_high_quality"
+	LargeTokens bool // stored as "model_large_tokens"
+}
+
+// GenerationConfig is a typed view of the well-known metadata keys. It is
+// stored in the prompt's metadata, so prompts configured through
+// SetMetadata and through SetGenerationConfig are interchangeable.
+//
+// Zero values mean "not set": nil pointers, empty strings, zero ints and
+// false hints are not written to the metadata.
+type GenerationConfig struct {
+	Model         string   // "model"
+	SystemContext string   // "system_context"
+	Language      string   // "lang_iso_6391", an ISO 639-1 code such as "en"
+	Temperature   *float64 // "temperature", between 0 and 2
+	TopP          *float64 // "top_p", between 0 and 1
+	MaxTokens     int      // "max_tokens"
+
+	MinRequiredWords         int    // "output_min_required_words"
+	ContinuationInstructions string // "continuation_instructions"
+
+	Hints ModelHints
+}
+
+// Validate checks the ranges of all set fields and returns every problem found
+func (c GenerationConfig) Validate() error {
+	var errs []error
+	if c.Language != "" && !iso6391[c.Language] {
+		errs = append(errs, fmt.Errorf("language %q is not a lowercase ISO 639-1 code", c.Language))
+	}
+	// written as !(in range) so NaN is rejected too
+	if c.Temperature != nil && !(*c.Temperature >= 0 && *c.Temperature <= 2) {
+		errs = append(errs, fmt.Errorf("temperature %v must be between 0 and 2", *c.Temperature))
+	}
+	if c.TopP != nil && !(*c.TopP >= 0 && *c.TopP <= 1) {
+		errs = append(errs, fmt.Errorf("top_p %v must be between 0 and 1", *c.TopP))
+	}
+	if c.MaxTokens < 0 {
+		errs = append(errs, fmt.Errorf("max_tokens %d must not be negative", c.MaxTokens))
+	}
+	if c.MinRequiredWords < 0 {
+		errs = append(errs, fmt.Errorf("output_min_required_words %d must not be negative", c.MinRequiredWords))
+	}
+	return errors.Join(errs...)
+}
+
+// SetGenerationConfig validates c and writes it to the metadata. Keys of
+// fields that are not set are removed, so the metadata afterwards describes
+// exactly c. Other metadata is left alone.
+func (p *Prompt) SetGenerationConfig(c GenerationConfig) error {
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("invalid generation config: %w", err)
+	}
+
+	setString := func(key Key[string], value string) {
+		if value == "" {
+			p.DeleteMetadata(string(key))
+		} else {
+			key.Set(p, value)
+		}
+	}
+	setInt := func(key Key[int], value int) {
+		if value == 0 {
+			p.DeleteMetadata(string(key))
+		} else {
+			key.Set(p, value)
+		}
+	}
+	setFloat := func(key Key[float64], value *float64) {
+		if value == nil {
+			p.DeleteMetadata(string(key))
+		} else {
+			key.Set(p, *value)
+		}
+	}
+	setBool := func(key Key[bool], value bool) {
+		if !value {
+			p.DeleteMetadata(string(key))
+		} else {
+			key.Set(p, value)
+		}
+	}
+
+	setString(MetaModel, c.Model)
+	setString(MetaSystemContext, c.SystemContext)
+	setString(MetaLanguage, c.Language)
+	setFloat(MetaTemperature, c.Temperature)
+	setFloat(MetaTopP, c.TopP)
+	setInt(MetaMaxTokens, c.MaxTokens)
+	setInt(MetaMinRequiredWords, c.MinRequiredWords)
+	setString(MetaContinuationInstructions, c.ContinuationInstructions)
+	setBool(MetaHighQuality, c.Hints.HighQuality)
+	setBool(MetaLargeTokens, c.Hints.LargeTokens)
+
+	return nil
+}
+
+// GenerationConfig reads the well-known keys from the metadata. Values of the
+// wrong type and values out of range are reported as errors; missing keys are
+// left at their zero value.
+func (p *Prompt) GenerationConfig() (GenerationConfig, error) {
+	var (
+		c    GenerationConfig
+		errs []error
+	)
+
+	get := func(err error) {
+		if err != nil && !errors.Is(err, ErrMetadataNotFound) {
+			errs = append(errs, err)
+		}
+	}
+	getFloat := func(key Key[float64]) *float64 {
+		value, err := key.Get(p)
+		if err != nil {
+			get(err)
+			return nil
+		}
+		return &value
+	}
+
+	var err error
+	c.Model, err = MetaModel.Get(p)
+	get(err)
+	c.SystemContext, err = MetaSystemContext.Get(p)
+	get(err)
+	c.Language, err = MetaLanguage.Get(p)
+	get(err)
+	c.Temperature = getFloat(MetaTemperature)
+	c.TopP = getFloat(MetaTopP)
+	c.MaxTokens, err = MetaMaxTokens.Get(p)
+	get(err)
+	c.MinRequiredWords, err = MetaMinRequiredWords.Get(p)
+	get(err)
+	c.ContinuationInstructions, err = MetaContinuationInstructions.Get(p)
+	get(err)
+	c.Hints.HighQuality, err = MetaHighQuality.Get(p)
+	get(err)
+	c.Hints.LargeTokens, err = MetaLargeTokens.Get(p)
+	get(err)
+
+	if err := c.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return c, fmt.Errorf("invalid generation config: %w", errors.Join(errs...))
+	}
+	return c, nil
+}
+
+// iso6391 holds all two-letter ISO 639-1 language codes
+var iso6391 = func() map[string]bool {
+	codes := strings.Fields(`
+		aa ab ae af ak am an ar as av ay az ba be bg bh bi bm bn bo br bs ca ce
+		ch co cr cs cu cv cy da de dv dz ee el en eo es et eu fa ff fi fj fo fr
+		fy ga gd gl gn gu gv ha he hi ho hr ht hu hy hz ia id ie ig ii ik io is
+		it iu ja jv ka kg ki kj kk kl km kn ko kr ks ku kv kw ky la lb lg li ln
+		lo lt lu lv mg mh mi mk ml mn mr ms mt my na nb nd ne ng nl nn no nr nv
+		ny oc oj om or os pa pi pl ps pt qu rm rn ro ru rw sa sc sd se sg si sk
+		sl sm sn so sq sr ss st su sv sw ta te tg th ti tk tl tn to tr ts tt tw
+		ty ug uk ur uz ve vi vo wa wo xh yi yo za zh zu`)
+	m := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		m[code] = true
+	}
+	return m
+}()
@@ -0,0 +1,134 @@
+package prompt
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestGenerationConfigRoundTrip(t *testing.T) {
+	c := GenerationConfig{
+		Model:                    "gpt-4",
+		SystemContext:            "You are a technical writer",
+		Language:                 "en",
+		Temperature:              floatPtr(0.7),
+		TopP:                     floatPtr(1),
+		MaxTokens:                2000,
+		MinRequiredWords:         500,
+		ContinuationInstructions: "Continue where you left off",
+		Hints:                    ModelHints{HighQuality: true},
+	}
+
+	p := NewPrompt()
+	p.SetMetadata("user_id", "u-1")
+	if err := p.SetGenerationConfig(c); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// the config is stored under the documented metadata keys
+	expected := map[string]any{
+		"model":                     "gpt-4",
+		"system_context":            "You are a technical writer",
+		"lang_iso_6391":             "en",
+		"temperature":               0.7,
+		"top_p":                     1.0,
+		"max_tokens":                2000,
+		"output_min_required_words": 500,
+		"continuation_instructions": "Continue where you left off",
+		"model_high_quality":        true,
+		"user_id":                   "u-1",
+	}
+	if !reflect.DeepEqual(p.GetAllMetadata(), expected) {
+		t.Errorf("Expected metadata %v, got %v", expected, p.GetAllMetadata())
+	}
+
+	got, err := p.GenerationConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, c) {
+		t.Errorf("Expected %+v, got %+v", c, got)
+	}
+
+	// clearing fields removes their keys
+	if err := p.SetGenerationConfig(GenerationConfig{Model: "gpt-4"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(p.GetAllMetadata()) != 2 {
+		t.Errorf("Expected only model and user_id to remain, got %v", p.GetAllMetadata())
+	}
+}
+
+func TestGenerationConfigFromMetadata(t *testing.T) {
+	// as decoded from JSON without type information
+	p := NewPrompt()
+	p.SetMetadata(TemperatureKey, json.Number("1"))
+	p.SetMetadata(MaxTokensKey, 150.0)
+	p.SetMetadata(LargeTokensKey, true)
+
+	c, err := p.GenerationConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.Temperature == nil || *c.Temperature != 1 || c.MaxTokens != 150 || !c.Hints.LargeTokens {
+		t.Errorf("Unexpected config %+v", c)
+	}
+}
+
+func TestGenerationConfigValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   GenerationConfig
+		contains string
+	}{
+		{name: "temperature too high", config: GenerationConfig{Temperature: floatPtr(2.5)}, contains: "temperature 2.5 must be between 0 and 2"},
+		{name: "negative temperature", config: GenerationConfig{Temperature: floatPtr(-0.1)}, contains: "temperature"},
+		{name: "top_p too high", config: GenerationConfig{TopP: floatPtr(1.5)}, contains: "top_p"},
+		{name: "NaN temperature", config: GenerationConfig{Temperature: floatPtr(math.NaN())}, contains: "temperature NaN"},
+		{name: "NaN top_p", config: GenerationConfig{TopP: floatPtr(math.NaN())}, contains: "top_p NaN"},
+		{name: "unknown language", config: GenerationConfig{Language: "xx"}, contains: `language "xx"`},
+		{name: "three-letter language", config: GenerationConfig{Language: "eng"}, contains: "ISO 639-1"},
+		{name: "uppercase language", config: GenerationConfig{Language: "EN"}, contains: "lowercase"},
+		{name: "negative max_tokens", config: GenerationConfig{MaxTokens: -1}, contains: "max_tokens"},
+		{name: "negative min words", config: GenerationConfig{MinRequiredWords: -5}, contains: "output_min_required_words"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPrompt()
+			err := p.SetGenerationConfig(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected error containing %q, got %v", tt.contains, err)
+			}
+			if len(p.GetAllMetadata()) != 0 {
+				t.Errorf("Expected invalid config not to be stored, got %v", p.GetAllMetadata())
+			}
+		})
+	}
+}
+
+func TestGenerationConfigInvalidMetadata(t *testing.T) {
+	p := NewPrompt()
+	p.SetMetadata(TemperatureKey, "0.7")
+	p.SetMetadata(LanguageKey, "english")
+	p.SetMetadata(ModelKey, "gpt-4")
+
+	c, err := p.GenerationConfig()
+	if err == nil {
+		t.Fatal("Expected error")
+	}
+	for _, want := range []string{`metadata "temperature"`, `language "english"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got %q", want, err.Error())
+		}
+	}
+	if c.Model != "gpt-4" {
+		t.Errorf("Expected valid fields to be read, got %+v", c)
+	}
+}
@@ -0,0 +1,102 @@
+package prompt
+
+import "strings"
+
+// Breakdown splits a word or token count into the parts of the rendered prompt
+type Breakdown struct {
+	Intro        int // section intros including the trailing colon
+	Instructions int // instruction text
+	DataBlocks   int // data block labels and content
+	Separators   int // bullet markers, code fences and "---" section separators
+}
+
+// Total returns the sum of all parts
+func (b Breakdown) Total() int {
+	return b.Intro + b.Instructions + b.DataBlocks + b.Separators
+}
+
+func (b Breakdown) add(o Breakdown) Breakdown {
+	return Breakdown{
+		Intro:        b.Intro + o.Intro,
+		Instructions: b.Instructions + o.Instructions,
+		DataBlocks:   b.DataBlocks + o.DataBlocks,
+		Separators:   b.Separators + o.Separators,
+	}
+}
+
+func (b Breakdown) scale(factor float64) Breakdown {
+	return Breakdown{
+		Intro:        int(float64(b.Intro) * factor),
+		Instructions: int(float64(b.Instructions) * factor),
+		DataBlocks:   int(float64(b.DataBlocks) * factor),
+		Separators:   int(float64(b.Separators) * factor),
+	}
+}
+
+// heuristicTokensPerWord is the multiplier used when no Tokenizer is configured
+const heuristicTokensPerWord = 1.4
+
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+// WordBreakdown counts the words of everything String emits, split by part
+func (s Section) WordBreakdown() Breakdown {
+	return s.breakdown(countWords)
+}
+
+// breakdown feeds every piece String emits to count and sums the results
+func (s Section) breakdown(count func(string) int) Breakdown {
+	var b Breakdown
+
+	if s.Intro != "" {
+		b.Intro = count(introLine(s.Intro))
+	}
+
+	for _, instruction := range s.Instructions {
+		b.Separators += count("-")
+		b.Instructions += count(string(instruction))
+	}
+
+	for _, block := range s.DataBlocks {
+		if block.Label != "" {
+			b.DataBlocks += count(block.Label + ":")
+		}
+		content := block.renderedContent()
+		fence := fenceFor(content)
+		b.DataBlocks += count(content)
+		b.Separators += count(fence+block.Type) + count(fence)
+		if block.Trust == Untrusted {
+			begin, end := block.delimiters()
+			b.Separators += count(untrustedPreamble) + count(begin) + count(end)
+		}
+	}
+
+	return b
+}
+
+// WordBreakdown counts the words of the rendered prompt, split by part
+func (p *Prompt) WordBreakdown() Breakdown {
+	var b Breakdown
+	for _, section := range p.Sections {
+		b = b.add(section.WordBreakdown())
+		b.Separators += countWords("---")
+	}
+	return b
+}
+
+// TokenBreakdown counts the tokens of the rendered prompt, split by part. Each
+// part is tokenized on its own, so the total can differ slightly from
+// TokenCount. Without a tokenizer every part is estimated from its word count.
+func (p *Prompt) TokenBreakdown() Breakdown {
+	if p.tokenizer == nil {
+		return p.WordBreakdown().scale(heuristicTokensPerWord)
+	}
+
+	var b Breakdown
+	for _, section := range p.Sections {
+		b = b.add(section.breakdown(p.tokenizer.CountTokens))
+		b.Separators += p.tokenizer.CountTokens("\n---\n")
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSectionWordBreakdown(t *testing.T) {
+	section := NewSection("Analyze the payload")
+	section.AddInstruction("Find all errors")
+	section.AddRawJSON("Payload data", `{"status": "failed", "code": 42}`)
+
+	expected := Breakdown{
+		Intro:        3,
+		Instructions: 3,
+		DataBlocks:   2 + 4,
+		Separators:   1 + 2,
+	}
+
+	actual := section.WordBreakdown()
+	if actual != expected {
+		t.Errorf("Expected %+v, got %+v", expected, actual)
+	}
+	if section.WordsCount() != expected.Total() {
+		t.Errorf("Expected WordsCount %d, got %d", expected.Total(), section.WordsCount())
+	}
+	if section.Intro != "Analyze the payload" {
+		t.Errorf("Counting must not modify the intro, got %q", section.Intro)
+	}
+}
+
+func TestWordCountMatchesRenderedOutput(t *testing.T) {
+	p := NewPrompt()
+
+	first := NewSection("Summarize")
+	first.AddInstruction("Keep it short")
+	if err := first.AddJSONData("Input", map[string]any{"text": "a long document with many words"}); err != nil {
+		t.Fatal(err)
+	}
+	p.AddSection(first)
+
+	second := NewSection("")
+	second.AddRawHTML("", "<p>Hello there</p>")
+	p.AddSection(second)
+
+	expected := len(strings.Fields(p.String()))
+	if p.WordCount() != expected {
+		t.Errorf("Expected WordCount %d to match rendered output, got %d", expected, p.WordCount())
+	}
+	if p.WordBreakdown().Separators != 2+2+2+1 {
+		t.Errorf("Unexpected separator count %+v", p.WordBreakdown())
+	}
+}
+
+func TestTokenBreakdown(t *testing.T) {
+	p := NewPrompt()
+	section := NewSection("Intro")
+	section.AddInstruction("one two three four five")
+	p.AddSection(section)
+
+	heuristic := p.TokenBreakdown()
+	if heuristic.Instructions != 7 {
+		t.Errorf("Expected 7 heuristic instruction tokens, got %+v", heuristic)
+	}
+
+	tok, err := LoadBPEFile(writeTestMergeTable(t), PatternCL100K)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetTokenizer(tok)
+
+	actual := p.TokenBreakdown()
+	if actual.Instructions != tok.CountTokens("one two three four five") {
+		t.Errorf("Unexpected instruction tokens %+v", actual)
+	}
+	if actual.Intro != tok.CountTokens("Intro:") {
+		t.Errorf("Unexpected intro tokens %+v", actual)
+	}
+}
@@ -0,0 +1,564 @@
+package prompt
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind describes how an element differs between two prompts
+type ChangeKind int
+
+const (
+	Unchanged ChangeKind = iota
+	Added
+	Removed
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Unchanged:
+		return "unchanged"
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Edit is one step of an edit script: a line kept (Unchanged), inserted
+// (Added) or deleted (Removed)
+type Edit struct {
+	Kind ChangeKind
+	Text string
+}
+
+// PromptDiff is the structural difference between two prompts, see Diff
+type PromptDiff struct {
+	// Sections lists the sections that were added, removed, modified or moved,
+	// in the order of the new prompt with removed sections at their old place
+	Sections []SectionDiff
+	// Metadata lists changed keys sorted by name
+	Metadata []MetadataChange
+}
+
+// SectionDiff describes the changes to one section. Sections are matched by
+// Intro; if several sections share an intro, they are matched in order.
+type SectionDiff struct {
+	Intro    string
+	Change   ChangeKind
+	OldIndex int  // index in the old prompt, -1 if added
+	NewIndex int  // index in the new prompt, -1 if removed
+	Moved    bool // the section changed its position relative to the others
+
+	// Set for added, removed and modified sections
+	Old, New Section
+
+	// Instructions is the edit script from old to new instructions, nil if
+	// they are unchanged
+	Instructions []Edit
+	DataBlocks   []DataBlockDiff
+	Fields       []FieldChange
+}
+
+// DataBlockDiff describes a changed data block. Blocks are matched by Label.
+type DataBlockDiff struct {
+	Label    string
+	Change   ChangeKind
+	Old, New DataBlock
+	// Content is the line by line edit script of the content
+	Content []Edit
+}
+
+// FieldChange is a changed section option such as Priority
+type FieldChange struct {
+	Field    string
+	Old, New any
+}
+
+// MetadataChange is an added, removed or modified metadata key
+type MetadataChange struct {
+	Key      string
+	Change   ChangeKind
+	Old, New any
+}
+
+// Empty reports whether the prompts are equal
+func (d *PromptDiff) Empty() bool {
+	return len(d.Sections) == 0 && len(d.Metadata) == 0
+}
+
+// Diff compares two prompts section by section. Sections are matched by
+// Intro, instructions and data block lines are compared with a line diff, and
+// data blocks are matched by Label. The tokenizer is not compared.
+func Diff(a, b *Prompt) *PromptDiff {
+	d := &PromptDiff{}
+
+	// match sections with the same intro in order of appearance
+	byIntro := make(map[string][]int)
+	for i, section := range a.Sections {
+		byIntro[section.Intro] = append(byIntro[section.Intro], i)
+	}
+	oldOf := make([]int, len(b.Sections))
+	matched := make([]bool, len(a.Sections))
+	for j, section := range b.Sections {
+		oldOf[j] = -1
+		if candidates := byIntro[section.Intro]; len(candidates) > 0 {
+			oldOf[j] = candidates[0]
+			byIntro[section.Intro] = candidates[1:]
+			matched[candidates[0]] = true
+		}
+	}
+	moved := movedSections(oldOf)
+
+	next := 0 // next old section that may have been removed
+	emitRemoved := func(upTo int) {
+		for ; next < upTo; next++ {
+			if !matched[next] {
+				d.Sections = append(d.Sections, SectionDiff{
+					Intro:    a.Sections[next].Intro,
+					Change:   Removed,
+					OldIndex: next,
+					NewIndex: -1,
+					Old:      a.Sections[next],
+				})
+			}
+		}
+	}
+
+	for j, section := range b.Sections {
+		i := oldOf[j]
+		if i < 0 {
+			// removals come before additions at the same place
+			upTo := len(a.Sections)
+			for k := j + 1; k < len(b.Sections); k++ {
+				if oldOf[k] >= 0 && !moved[k] {
+					upTo = oldOf[k]
+					break
+				}
+			}
+			emitRemoved(upTo)
+
+			d.Sections = append(d.Sections, SectionDiff{
+				Intro:    section.Intro,
+				Change:   Added,
+				OldIndex: -1,
+				NewIndex: j,
+				New:      section,
+			})
+			continue
+		}
+		if !moved[j] {
+			// sections kept in order anchor the removed sections around them
+			emitRemoved(i)
+			next = i + 1
+		}
+
+		sd := diffSection(a.Sections[i], section)
+		sd.OldIndex, sd.NewIndex, sd.Moved = i, j, moved[j]
+		if sd.Change != Unchanged || sd.Moved {
+			d.Sections = append(d.Sections, sd)
+		}
+	}
+	emitRemoved(len(a.Sections))
+
+	d.Metadata = diffMetadata(a.metadata, b.metadata)
+	return d
+}
+
+// movedSections marks the matched sections that are not part of the longest
+// run kept in the old order. oldOf maps new indexes to old indexes (-1 for
+// added sections).
+func movedSections(oldOf []int) []bool {
+	// longest increasing subsequence of old indexes, O(n²) is fine for prompts
+	length := make([]int, len(oldOf))
+	prev := make([]int, len(oldOf))
+	best := -1
+	for j := range oldOf {
+		prev[j] = -1
+		if oldOf[j] < 0 {
+			continue
+		}
+		length[j] = 1
+		for k := 0; k < j; k++ {
+			if oldOf[k] >= 0 && oldOf[k] < oldOf[j] && length[k]+1 > length[j] {
+				length[j], prev[j] = length[k]+1, k
+			}
+		}
+		// on ties prefer the run ending earlier in the old prompt, so a
+		// section pulled forward counts as moved rather than those it passed
+		if best < 0 || length[j] > length[best] || (length[j] == length[best] && oldOf[j] < oldOf[best]) {
+			best = j
+		}
+	}
+
+	moved := make([]bool, len(oldOf))
+	for j := range oldOf {
+		moved[j] = oldOf[j] >= 0
+	}
+	for j := best; j >= 0; j = prev[j] {
+		moved[j] = false
+	}
+	return moved
+}
+
+func diffSection(old, new Section) SectionDiff {
+	sd := SectionDiff{Intro: new.Intro}
+
+	oldInstructions := make([]string, len(old.Instructions))
+	for i, instruction := range old.Instructions {
+		oldInstructions[i] = string(instruction)
+	}
+	newInstructions := make([]string, len(new.Instructions))
+	for i, instruction := range new.Instructions {
+		newInstructions[i] = string(instruction)
+	}
+	if edits := diffLines(oldInstructions, newInstructions); hasChanges(edits) {
+		sd.Instructions = edits
+	}
+
+	sd.DataBlocks = diffDataBlocks(old.DataBlocks, new.DataBlocks)
+
+	if old.Priority != new.Priority {
+		sd.Fields = append(sd.Fields, FieldChange{Field: "priority", Old: old.Priority, New: new.Priority})
+	}
+	if old.Required != new.Required {
+		sd.Fields = append(sd.Fields, FieldChange{Field: "required", Old: old.Required, New: new.Required})
+	}
+	if old.PinToEnd != new.PinToEnd {
+		sd.Fields = append(sd.Fields, FieldChange{Field: "pin_to_end", Old: old.PinToEnd, New: new.PinToEnd})
+	}
+
+	if sd.Instructions != nil || sd.DataBlocks != nil || sd.Fields != nil {
+		sd.Change = Modified
+		sd.Old, sd.New = old, new
+	}
+	return sd
+}
+
+func diffDataBlocks(old, new []DataBlock) []DataBlockDiff {
+	byLabel := make(map[string][]int)
+	for i, block := range old {
+		byLabel[block.Label] = append(byLabel[block.Label], i)
+	}
+	matched := make([]bool, len(old))
+
+	var diffs []DataBlockDiff
+	for _, block := range new {
+		candidates := byLabel[block.Label]
+		if len(candidates) == 0 {
+			diffs = append(diffs, DataBlockDiff{
+				Label:   block.Label,
+				Change:  Added,
+				New:     block,
+				Content: diffLines(nil, strings.Split(block.Content, "\n")),
+			})
+			continue
+		}
+		byLabel[block.Label] = candidates[1:]
+		matched[candidates[0]] = true
+
+		prev := old[candidates[0]]
+		if prev == block {
+			continue
+		}
+		diffs = append(diffs, DataBlockDiff{
+			Label:   block.Label,
+			Change:  Modified,
+			Old:     prev,
+			New:     block,
+			Content: diffLines(strings.Split(prev.Content, "\n"), strings.Split(block.Content, "\n")),
+		})
+	}
+
+	for i, block := range old {
+		if !matched[i] {
+			diffs = append(diffs, DataBlockDiff{
+				Label:   block.Label,
+				Change:  Removed,
+				Old:     block,
+				Content: diffLines(strings.Split(block.Content, "\n"), nil),
+			})
+		}
+	}
+	return diffs
+}
+
+func diffMetadata(old, new map[string]any) []MetadataChange {
+	var changes []MetadataChange
+	for key, value := range old {
+		newValue, ok := new[key]
+		switch {
+		case !ok:
+			changes = append(changes, MetadataChange{Key: key, Change: Removed, Old: value})
+		case !reflect.DeepEqual(value, newValue):
+			changes = append(changes, MetadataChange{Key: key, Change: Modified, Old: value, New: newValue})
+		}
+	}
+	for key, value := range new {
+		if _, ok := old[key]; !ok {
+			changes = append(changes, MetadataChange{Key: key, Change: Added, New: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// maxDiffCells limits the size of the LCS table. Larger inputs are reported as
+// replaced entirely instead of spending quadratic time and memory.
+const maxDiffCells = 1 << 22
+
+// diffLines returns the shortest edit script turning a into b
+func diffLines(a, b []string) []Edit {
+	var prefix, suffix []Edit
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, Edit{Kind: Unchanged, Text: a[0]})
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		suffix = append(suffix, Edit{Kind: Unchanged, Text: a[len(a)-1]})
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	edits := prefix
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			edits = append(edits, Edit{Kind: Removed, Text: line})
+		}
+		for _, line := range b {
+			edits = append(edits, Edit{Kind: Added, Text: line})
+		}
+	} else {
+		edits = append(edits, lcsEdits(a, b)...)
+	}
+
+	for i := len(suffix) - 1; i >= 0; i-- {
+		edits = append(edits, suffix[i])
+	}
+	return edits
+}
+
+// lcsEdits builds an edit script from the longest common subsequence of a and b
+func lcsEdits(a, b []string) []Edit {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []Edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, Edit{Kind: Unchanged, Text: a[i]})
+			i, j = i+1, j+1
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			edits = append(edits, Edit{Kind: Added, Text: b[j]})
+			j++
+		default:
+			edits = append(edits, Edit{Kind: Removed, Text: a[i]})
+			i++
+		}
+	}
+
+	// removals read better before the additions that replace them
+	for k := 1; k < len(edits); k++ {
+		for m := k; m > 0 && edits[m].Kind == Removed && edits[m-1].Kind == Added; m-- {
+			edits[m], edits[m-1] = edits[m-1], edits[m]
+		}
+	}
+	return edits
+}
+
+func hasChanges(edits []Edit) bool {
+	for _, edit := range edits {
+		if edit.Kind != Unchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// diffContext is the number of unchanged lines shown around changes in String
+const diffContext = 3
+
+// String renders the diff in a unified diff style with one hunk per changed
+// section and one for metadata. Lines are prefixed with ' ', '-' or '+'.
+func (d *PromptDiff) String() string {
+	if d.Empty() {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("--- a\n+++ b\n")
+
+	for _, sd := range d.Sections {
+		b.WriteString("@@ " + sd.header() + " @@\n")
+		writeHunk(&b, sd.lines())
+	}
+
+	if len(d.Metadata) > 0 {
+		b.WriteString("@@ metadata @@\n")
+		for _, change := range d.Metadata {
+			if change.Change != Added {
+				fmt.Fprintf(&b, "-%s: %v\n", change.Key, change.Old)
+			}
+			if change.Change != Removed {
+				fmt.Fprintf(&b, "+%s: %v\n", change.Key, change.New)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func (sd SectionDiff) header() string {
+	name := "section"
+	if sd.Intro != "" {
+		name += fmt.Sprintf(" %q", sd.Intro)
+	}
+
+	var parts []string
+	if sd.Moved {
+		parts = append(parts, fmt.Sprintf("moved from %d to %d", sd.OldIndex+1, sd.NewIndex+1))
+	}
+	if sd.Change != Unchanged {
+		parts = append(parts, sd.Change.String())
+	}
+	return name + " " + strings.Join(parts, ", ")
+}
+
+// lines returns the hunk body of the section as edits
+func (sd SectionDiff) lines() []Edit {
+	switch sd.Change {
+	case Added:
+		return splitEdits(Added, sd.New.String())
+	case Removed:
+		return splitEdits(Removed, sd.Old.String())
+	case Unchanged:
+		return nil
+	}
+
+	var lines []Edit
+	if sd.Intro != "" {
+		lines = append(lines, Edit{Kind: Unchanged, Text: introLine(sd.Intro)})
+	}
+	for _, field := range sd.Fields {
+		lines = append(lines,
+			Edit{Kind: Removed, Text: fmt.Sprintf("(%s: %v)", field.Field, field.Old)},
+			Edit{Kind: Added, Text: fmt.Sprintf("(%s: %v)", field.Field, field.New)},
+		)
+	}
+	for _, edit := range sd.Instructions {
+		lines = append(lines, splitEdits(edit.Kind, "- "+edit.Text)...)
+	}
+
+	for _, block := range sd.DataBlocks {
+		header := func(kind ChangeKind, b DataBlock) {
+			if b.Label != "" {
+				lines = append(lines, Edit{Kind: kind, Text: b.Label + ":"})
+			}
+			lines = append(lines, Edit{Kind: kind, Text: "```" + b.Type})
+		}
+		switch block.Change {
+		case Added:
+			header(Added, block.New)
+		case Removed:
+			header(Removed, block.Old)
+		default:
+			if block.Old.Type == block.New.Type {
+				header(Unchanged, block.New)
+				break
+			}
+			if block.Label != "" {
+				lines = append(lines, Edit{Kind: Unchanged, Text: block.Label + ":"})
+			}
+			lines = append(lines,
+				Edit{Kind: Removed, Text: "```" + block.Old.Type},
+				Edit{Kind: Added, Text: "```" + block.New.Type},
+			)
+		}
+		if block.Change == Modified && block.Old.Trust != block.New.Trust {
+			lines = append(lines,
+				Edit{Kind: Removed, Text: fmt.Sprintf("(trust: %s)", block.Old.Trust)},
+				Edit{Kind: Added, Text: fmt.Sprintf("(trust: %s)", block.New.Trust)},
+			)
+		}
+		lines = append(lines, block.Content...)
+
+		closing := Unchanged
+		if block.Change == Added || block.Change == Removed {
+			closing = block.Change
+		}
+		lines = append(lines, Edit{Kind: closing, Text: "```"})
+	}
+	return lines
+}
+
+// splitEdits turns possibly multi-line text into one edit per line
+func splitEdits(kind ChangeKind, text string) []Edit {
+	parts := strings.Split(text, "\n")
+	edits := make([]Edit, len(parts))
+	for i, part := range parts {
+		edits[i] = Edit{Kind: kind, Text: part}
+	}
+	return edits
+}
+
+// writeHunk writes edits with their prefixes, shortening long runs of
+// unchanged lines to diffContext lines around each change
+func writeHunk(b *strings.Builder, edits []Edit) {
+	for i := 0; i < len(edits); {
+		if edits[i].Kind != Unchanged {
+			prefix := "+"
+			if edits[i].Kind == Removed {
+				prefix = "-"
+			}
+			b.WriteString(prefix + edits[i].Text + "\n")
+			i++
+			continue
+		}
+
+		end := i
+		for end < len(edits) && edits[end].Kind == Unchanged {
+			end++
+		}
+
+		keepStart, keepEnd := diffContext, diffContext
+		if i == 0 {
+			keepStart = 0
+		}
+		if end == len(edits) {
+			keepEnd = 0
+		}
+		if end-i <= keepStart+keepEnd {
+			keepStart, keepEnd = end-i, 0
+		}
+
+		for k := i; k < i+keepStart; k++ {
+			b.WriteString(" " + edits[k].Text + "\n")
+		}
+		if keepStart+keepEnd < end-i {
+			b.WriteString(" ...\n")
+		}
+		for k := end - keepEnd; k < end; k++ {
+			b.WriteString(" " + edits[k].Text + "\n")
+		}
+		i = end
+	}
+}
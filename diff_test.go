@@ -0,0 +1,215 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffEqual(t *testing.T) {
+	d := Diff(newTestPrompt(), newTestPrompt())
+
+	if !d.Empty() {
+		t.Errorf("Expected empty diff, got %+v", d)
+	}
+	if d.String() != "" {
+		t.Errorf("Expected empty text, got %q", d.String())
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := NewPrompt()
+	summary := NewSection("Task")
+	summary.AddInstruction("Summarize the input")
+	summary.AddInstruction("Keep it short")
+	summary.AddRawJSON("Input", "{\n  \"id\": 1,\n  \"name\": \"a\"\n}")
+	a.AddSection(summary)
+	a.AddSection(Section{Intro: "Style", Instructions: []Instruction{"Be friendly"}})
+	a.AddSection(Section{Intro: "Examples", Instructions: []Instruction{"Input: x, output: y"}})
+	a.AddSection(Section{Intro: "Output", Instructions: []Instruction{"Use JSON"}})
+	a.SetMetadata(ModelKey, "gpt-4")
+	a.SetMetadata(TemperatureKey, 0.7)
+	b := a.Clone()
+
+	// edit Task: replace an instruction and change the data
+	b.Sections[0].Instructions[1] = "Keep it under 50 words"
+	b.Sections[0].DataBlocks[0].Content = "{\n  \"id\": 2,\n  \"name\": \"a\"\n}"
+	b.Sections[0].Priority = 1
+	// move Output before Style, drop Examples, add Tone
+	b.Sections = []Section{b.Sections[0], b.Sections[3], b.Sections[1], {Intro: "Tone", Instructions: []Instruction{"Formal"}}}
+	b.SetMetadata(TemperatureKey, 0.2)
+	b.DeleteMetadata(ModelKey)
+	b.SetMetadata(TopPKey, 0.9)
+
+	d := Diff(a, b)
+
+	if len(d.Sections) != 4 {
+		t.Fatalf("Expected 4 section changes, got %+v", d.Sections)
+	}
+
+	task := d.Sections[0]
+	if task.Intro != "Task" || task.Change != Modified || task.Moved {
+		t.Errorf("Unexpected task diff %+v", task)
+	}
+	expectedInstructions := []Edit{
+		{Kind: Unchanged, Text: "Summarize the input"},
+		{Kind: Removed, Text: "Keep it short"},
+		{Kind: Added, Text: "Keep it under 50 words"},
+	}
+	if !reflect.DeepEqual(task.Instructions, expectedInstructions) {
+		t.Errorf("Expected instruction edits %v, got %v", expectedInstructions, task.Instructions)
+	}
+	if len(task.DataBlocks) != 1 || task.DataBlocks[0].Label != "Input" || task.DataBlocks[0].Change != Modified {
+		t.Errorf("Unexpected data block diff %+v", task.DataBlocks)
+	}
+	if !reflect.DeepEqual(task.Fields, []FieldChange{{Field: "priority", Old: 0, New: 1}}) {
+		t.Errorf("Unexpected field changes %+v", task.Fields)
+	}
+
+	output := d.Sections[1]
+	if output.Intro != "Output" || output.Change != Unchanged || !output.Moved || output.OldIndex != 3 || output.NewIndex != 1 {
+		t.Errorf("Expected Output to be moved from 3 to 1, got %+v", output)
+	}
+
+	examples := d.Sections[2]
+	if examples.Intro != "Examples" || examples.Change != Removed || examples.OldIndex != 2 {
+		t.Errorf("Expected Examples to be removed, got %+v", examples)
+	}
+
+	tone := d.Sections[3]
+	if tone.Intro != "Tone" || tone.Change != Added || tone.NewIndex != 3 {
+		t.Errorf("Expected Tone to be added, got %+v", tone)
+	}
+
+	expectedMetadata := []MetadataChange{
+		{Key: ModelKey, Change: Removed, Old: "gpt-4"},
+		{Key: TemperatureKey, Change: Modified, Old: 0.7, New: 0.2},
+		{Key: TopPKey, Change: Added, New: 0.9},
+	}
+	if !reflect.DeepEqual(d.Metadata, expectedMetadata) {
+		t.Errorf("Expected metadata changes %+v, got %+v", expectedMetadata, d.Metadata)
+	}
+
+	expectedText := `--- a
++++ b
+@@ section "Task" modified @@
+ Task:
+-(priority: 0)
++(priority: 1)
+ - Summarize the input
+-- Keep it short
++- Keep it under 50 words
+ Input:
+ ` + "```json" + `
+ {
+-  "id": 1,
++  "id": 2,
+   "name": "a"
+ }
+ ` + "```" + `
+@@ section "Output" moved from 4 to 2 @@
+@@ section "Examples" removed @@
+-Examples:
+-- Input: x, output: y
+@@ section "Tone" added @@
++Tone:
++- Formal
+@@ metadata @@
+-model: gpt-4
+-temperature: 0.7
++temperature: 0.2
++top_p: 0.9
+`
+	if d.String() != expectedText {
+		t.Errorf("Expected text:\n%s\nGot:\n%s", expectedText, d.String())
+	}
+}
+
+func TestDiffDataBlocks(t *testing.T) {
+	a := NewPrompt()
+	section := NewSection("Docs")
+	section.AddRawJSON("Keep", "{}")
+	section.AddRawJSON("Drop", "{}")
+	section.AddRawJSON("Retype", "<a/>")
+	a.AddSection(section)
+
+	b := a.Clone()
+	b.Sections[0].DataBlocks = []DataBlock{
+		{Label: "Keep", Content: "{}", Type: "json", Trust: Untrusted},
+		{Label: "Retype", Content: "<a/>", Type: "xml"},
+		{Label: "New", Content: "<p>hi</p>", Type: "html"},
+	}
+
+	d := Diff(a, b)
+	blocks := d.Sections[0].DataBlocks
+	if len(blocks) != 4 {
+		t.Fatalf("Expected 4 data block changes, got %+v", blocks)
+	}
+	if blocks[0].Label != "Keep" || blocks[0].Change != Modified || blocks[0].New.Trust != Untrusted {
+		t.Errorf("Expected Keep to become untrusted, got %+v", blocks[0])
+	}
+	if !strings.Contains(d.String(), "-(trust: trusted)\n+(trust: untrusted)\n") {
+		t.Errorf("Expected trust change in text, got %q", d.String())
+	}
+	blocks = blocks[1:]
+	if blocks[0].Label != "Retype" || blocks[0].Change != Modified || blocks[0].Old.Type != "json" || blocks[0].New.Type != "xml" {
+		t.Errorf("Expected Retype to change type, got %+v", blocks[0])
+	}
+	if blocks[1].Label != "New" || blocks[1].Change != Added {
+		t.Errorf("Expected New to be added, got %+v", blocks[1])
+	}
+	if blocks[2].Label != "Drop" || blocks[2].Change != Removed {
+		t.Errorf("Expected Drop to be removed, got %+v", blocks[2])
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []string
+		want []Edit
+	}{
+		{
+			name: "insert in the middle",
+			a:    []string{"a", "c"},
+			b:    []string{"a", "b", "c"},
+			want: []Edit{{Unchanged, "a"}, {Added, "b"}, {Unchanged, "c"}},
+		},
+		{
+			name: "replace",
+			a:    []string{"a", "b", "c"},
+			b:    []string{"a", "x", "c"},
+			want: []Edit{{Unchanged, "a"}, {Removed, "b"}, {Added, "x"}, {Unchanged, "c"}},
+		},
+		{
+			name: "from empty",
+			a:    nil,
+			b:    []string{"a"},
+			want: []Edit{{Added, "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDiffContext(t *testing.T) {
+	var lines []Edit
+	for i := 0; i < 10; i++ {
+		lines = append(lines, Edit{Kind: Unchanged, Text: string(rune('a' + i))})
+	}
+	lines[5].Kind = Removed
+
+	var b strings.Builder
+	writeHunk(&b, lines)
+
+	expected := " ...\n c\n d\n e\n-f\n g\n h\n i\n ...\n"
+	if b.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, b.String())
+	}
+}
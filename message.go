@@ -0,0 +1,93 @@
+package prompt
+
+// Role identifies the author of a chat message
+type Role string
+
+const (
+	RoleSystem    Role = "system"
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+// Content is anything that can be rendered into a message body, such as a
+// *Prompt, a Section or plain Text.
+type Content interface {
+	Render(r Renderer) (string, error)
+}
+
+// Text is literal message content that renders as-is
+type Text string
+
+// Render returns the text unchanged. If r is a TextChecker, the text is
+// checked first.
+func (t Text) Render(r Renderer) (string, error) {
+	if checker, ok := r.(TextChecker); ok {
+		if err := checker.CheckText(string(t)); err != nil {
+			return "", err
+		}
+	}
+	return string(t), nil
+}
+
+// TextChecker is implemented by renderers that need to see plain Text content,
+// which is otherwise passed through without reaching the renderer, such as
+// SecretGuard
+type TextChecker interface {
+	CheckText(text string) error
+}
+
+// Message is a single role-tagged chat message
+type Message struct {
+	Role    Role
+	Content Content
+}
+
+// Render formats the message content with the given renderer
+func (m Message) Render(r Renderer) (string, error) {
+	if m.Content == nil {
+		return "", nil
+	}
+	return m.Content.Render(r)
+}
+
+// Conversation holds an ordered list of chat messages
+type Conversation struct {
+	Messages []Message
+}
+
+func NewConversation() *Conversation {
+	return &Conversation{
+		Messages: []Message{},
+	}
+}
+
+// AddMessage appends a message with the given role
+func (c *Conversation) AddMessage(role Role, content Content) {
+	c.Messages = append(c.Messages, Message{Role: role, Content: content})
+}
+
+// AddSystem appends a system message
+func (c *Conversation) AddSystem(content Content) {
+	c.AddMessage(RoleSystem, content)
+}
+
+// AddUser appends a user message
+func (c *Conversation) AddUser(content Content) {
+	c.AddMessage(RoleUser, content)
+}
+
+// AddAssistant appends an assistant message
+func (c *Conversation) AddAssistant(content Content) {
+	c.AddMessage(RoleAssistant, content)
+}
+
+// ToMessages turns the prompt into a conversation: the "system_context"
+// metadata becomes a system message and the prompt itself the user message.
+func (p *Prompt) ToMessages() *Conversation {
+	c := NewConversation()
+	if system := p.GetMetadataString(SystemContextKey); system != "" {
+		c.AddSystem(Text(system))
+	}
+	c.AddUser(p)
+	return c
+}
@@ -0,0 +1,74 @@
+package prompt
+
+import "testing"
+
+func TestPromptToMessages(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{Intro: "Task", Instructions: []Instruction{"Say hello"}})
+	p.SetMetadata(SystemContextKey, "You are a helpful assistant")
+
+	c := p.ToMessages()
+	if len(c.Messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(c.Messages))
+	}
+
+	if c.Messages[0].Role != RoleSystem {
+		t.Errorf("Expected first message to be system, got %s", c.Messages[0].Role)
+	}
+	system, err := c.Messages[0].Render(TextRenderer{})
+	if err != nil || system != "You are a helpful assistant" {
+		t.Errorf("Unexpected system message %q (err %v)", system, err)
+	}
+
+	if c.Messages[1].Role != RoleUser {
+		t.Errorf("Expected second message to be user, got %s", c.Messages[1].Role)
+	}
+	user, err := c.Messages[1].Render(TextRenderer{})
+	if err != nil || user != p.String() {
+		t.Errorf("Expected user message to be the rendered prompt, got %q (err %v)", user, err)
+	}
+}
+
+func TestPromptToMessagesWithoutSystemContext(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{Intro: "Task", Instructions: []Instruction{"Say hello"}})
+
+	c := p.ToMessages()
+	if len(c.Messages) != 1 || c.Messages[0].Role != RoleUser {
+		t.Errorf("Expected a single user message, got %+v", c.Messages)
+	}
+}
+
+func TestConversation(t *testing.T) {
+	section := NewSection("Examples")
+	section.AddInstruction("Answer in one word")
+
+	c := NewConversation()
+	c.AddSystem(Text("Be brief"))
+	c.AddUser(section)
+	c.AddAssistant(Text("Okay"))
+	c.AddUser(&section)
+
+	expectedRoles := []Role{RoleSystem, RoleUser, RoleAssistant, RoleUser}
+	if len(c.Messages) != len(expectedRoles) {
+		t.Fatalf("Expected %d messages, got %d", len(expectedRoles), len(c.Messages))
+	}
+	for i, role := range expectedRoles {
+		if c.Messages[i].Role != role {
+			t.Errorf("Message %d: expected role %s, got %s", i, role, c.Messages[i].Role)
+		}
+	}
+
+	md, err := c.Messages[1].Render(MarkdownRenderer{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if md != "## Examples\n\n- Answer in one word\n" {
+		t.Errorf("Unexpected section rendering %q", md)
+	}
+
+	empty, err := Message{Role: RoleUser}.Render(TextRenderer{})
+	if err != nil || empty != "" {
+		t.Errorf("Expected empty content for nil message, got %q (err %v)", empty, err)
+	}
+}
@@ -0,0 +1,183 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// Key is a metadata key whose values have type T. Typed keys catch type
+// mistakes at compile time when setting and report them when getting:
+//
+//	prompt.MetaTemperature.Set(p, 0.7)
+//	temperature, err := prompt.MetaTemperature.Get(p)
+type Key[T any] string
+
+// Typed versions of the well-known metadata keys
+var (
+	MetaSystemContext = Key[string](SystemContextKey)
+	MetaModel         = Key[string](ModelKey)
+	MetaTemperature   = Key[float64](TemperatureKey)
+	MetaMaxTokens     = Key[int](MaxTokensKey)
+	MetaTopP          = Key[float64](TopPKey)
+
+	MetaLanguage                 = Key[string](LanguageKey)
+	MetaMinRequiredWords         = Key[int](MinRequiredWordsKey)
+	MetaContinuationInstructions = Key[string](ContinuationInstructionsKey)
+	MetaHighQuality              = Key[bool](HighQualityKey)
+	MetaLargeTokens              = Key[bool](LargeTokensKey)
+)
+
+// Get returns the value of the key, see GetMetadataAs
+func (k Key[T]) Get(p *Prompt) (T, error) {
+	return GetMetadataAs[T](p, string(k))
+}
+
+// Set stores value under the key
+func (k Key[T]) Set(p *Prompt, value T) {
+	p.SetMetadata(string(k), value)
+}
+
+// ErrMetadataNotFound is returned by GetMetadataAs for keys that are not set
+var ErrMetadataNotFound = errors.New("metadata not found")
+
+// MetadataTypeError reports a metadata value that cannot be used as the
+// requested type
+type MetadataTypeError struct {
+	Key   string
+	Value any
+	Want  string // requested type
+}
+
+func (e *MetadataTypeError) Error() string {
+	return fmt.Sprintf("metadata %q: cannot use %T %v as %s", e.Key, e.Value, e.Value, e.Want)
+}
+
+// GetMetadataAs returns the metadata value of key as T. Numbers are converted
+// between all integer and float types (including json.Number) as long as the
+// value fits: 200.0 is a valid int, 10.5 and 300 as int8 are not. Strings are
+// never parsed as numbers. Missing keys return an error wrapping
+// ErrMetadataNotFound; other mismatches return a *MetadataTypeError.
+func GetMetadataAs[T any](p *Prompt, key string) (T, error) {
+	var zero T
+	value, ok := p.GetMetadata(key)
+	if !ok {
+		return zero, fmt.Errorf("metadata %q: %w", key, ErrMetadataNotFound)
+	}
+	if v, ok := value.(T); ok {
+		return v, nil
+	}
+
+	target := reflect.TypeOf(&zero).Elem()
+	if v, ok := convertNumber(value, target); ok {
+		return v.Interface().(T), nil
+	}
+	return zero, &MetadataTypeError{Key: key, Value: value, Want: target.String()}
+}
+
+// convertNumber converts a numeric value to the numeric type target and
+// reports whether this was possible without losing information
+func convertNumber(value any, target reflect.Type) (reflect.Value, bool) {
+	src := reflect.ValueOf(value)
+	switch n := value.(type) {
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			src = reflect.ValueOf(i)
+		} else if f, err := n.Float64(); err == nil {
+			src = reflect.ValueOf(f)
+		} else {
+			return reflect.Value{}, false
+		}
+	case float32:
+		// use the shortest decimal form so float32(0.7) becomes 0.7, not 0.699999988
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(n), 'g', -1, 32), 64)
+		src = reflect.ValueOf(f)
+	}
+
+	out := reflect.New(target).Elem()
+	switch {
+	case isInt(target.Kind()):
+		var i int64
+		switch {
+		case isInt(src.Kind()):
+			i = src.Int()
+		case isUint(src.Kind()):
+			if src.Uint() > math.MaxInt64 {
+				return reflect.Value{}, false
+			}
+			i = int64(src.Uint())
+		case isFloat(src.Kind()):
+			f := src.Float()
+			if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+				return reflect.Value{}, false
+			}
+			i = int64(f)
+		default:
+			return reflect.Value{}, false
+		}
+		if out.OverflowInt(i) {
+			return reflect.Value{}, false
+		}
+		out.SetInt(i)
+
+	case isUint(target.Kind()):
+		var u uint64
+		switch {
+		case isInt(src.Kind()):
+			if src.Int() < 0 {
+				return reflect.Value{}, false
+			}
+			u = uint64(src.Int())
+		case isUint(src.Kind()):
+			u = src.Uint()
+		case isFloat(src.Kind()):
+			f := src.Float()
+			if f != math.Trunc(f) || f < 0 || f >= math.MaxUint64 {
+				return reflect.Value{}, false
+			}
+			u = uint64(f)
+		default:
+			return reflect.Value{}, false
+		}
+		if out.OverflowUint(u) {
+			return reflect.Value{}, false
+		}
+		out.SetUint(u)
+
+	case isFloat(target.Kind()):
+		var f float64
+		switch {
+		case isInt(src.Kind()):
+			f = float64(src.Int())
+		case isUint(src.Kind()):
+			f = float64(src.Uint())
+		case isFloat(src.Kind()):
+			f = src.Float()
+		default:
+			return reflect.Value{}, false
+		}
+		if out.OverflowFloat(f) {
+			return reflect.Value{}, false
+		}
+		out.SetFloat(f)
+
+	default:
+		return reflect.Value{}, false
+	}
+	return out, true
+}
+
+func isInt(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUint(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
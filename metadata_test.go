@@ -0,0 +1,123 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestGetMetadataAsNumbers(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		get   func(p *Prompt) (any, error)
+		want  any
+	}{
+		{name: "int64 as int", value: int64(42), get: getAs[int], want: 42},
+		{name: "whole float64 as int", value: float64(200), get: getAs[int], want: 200},
+		{name: "json.Number as int", value: json.Number("7"), get: getAs[int], want: 7},
+		{name: "json.Number as float64", value: json.Number("0.25"), get: getAs[float64], want: 0.25},
+		{name: "int as float64", value: 2, get: getAs[float64], want: 2.0},
+		{name: "float32 as float64", value: float32(0.7), get: getAs[float64], want: 0.7},
+		{name: "uint8 as int64", value: uint8(255), get: getAs[int64], want: int64(255)},
+		{name: "int as uint", value: 3, get: getAs[uint], want: uint(3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPrompt()
+			p.SetMetadata("key", tt.value)
+
+			got, err := tt.get(p)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v (%T), got %v (%T)", tt.want, tt.want, got, got)
+			}
+		})
+	}
+}
+
+func TestGetMetadataAsMismatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		get   func(p *Prompt) (any, error)
+	}{
+		{name: "string as float64", value: "0.7", get: getAs[float64]},
+		{name: "fractional float as int", value: 10.5, get: getAs[int]},
+		{name: "overflow", value: 300, get: getAs[int8]},
+		{name: "negative as uint", value: -1, get: getAs[uint]},
+		{name: "int as string", value: 4, get: getAs[string]},
+		{name: "json.Number as string", value: json.Number("4"), get: getAs[string]},
+		{name: "string as bool", value: "true", get: getAs[bool]},
+		{name: "nil as string", value: nil, get: getAs[string]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPrompt()
+			p.SetMetadata("key", tt.value)
+
+			_, err := tt.get(p)
+			var typeErr *MetadataTypeError
+			if !errors.As(err, &typeErr) || typeErr.Key != "key" {
+				t.Errorf("Expected *MetadataTypeError for key, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetMetadataAsMissing(t *testing.T) {
+	_, err := GetMetadataAs[string](NewPrompt(), "missing")
+	if !errors.Is(err, ErrMetadataNotFound) {
+		t.Errorf("Expected ErrMetadataNotFound, got %v", err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	p := NewPrompt()
+	MetaTemperature.Set(p, 0.7)
+	MetaModel.Set(p, "gpt-4")
+
+	temperature, err := MetaTemperature.Get(p)
+	if err != nil || temperature != 0.7 {
+		t.Errorf("Expected 0.7, got %v (err %v)", temperature, err)
+	}
+	if got := p.GetMetadataString(ModelKey); got != "gpt-4" {
+		t.Errorf("Expected 'gpt-4', got %q", got)
+	}
+
+	p.SetMetadata(TemperatureKey, "0.7")
+	if _, err := MetaTemperature.Get(p); err == nil {
+		t.Error("Expected error for temperature stored as string")
+	}
+}
+
+func TestGetMetadataIntAndFloat(t *testing.T) {
+	p := NewPrompt()
+	p.SetMetadata("int64", int64(5))
+	p.SetMetadata("float", 3.0)
+	p.SetMetadata(TemperatureKey, 0.7)
+
+	if got := p.GetMetadataInt("int64"); got != 5 {
+		t.Errorf("Expected 5, got %d", got)
+	}
+	if got := p.GetMetadataInt("float"); got != 3 {
+		t.Errorf("Expected 3, got %d", got)
+	}
+	if got := p.GetMetadataInt(TemperatureKey); got != 0 {
+		t.Errorf("Expected 0 for fractional value, got %d", got)
+	}
+	if got := p.GetMetadataFloat(TemperatureKey); got != 0.7 {
+		t.Errorf("Expected 0.7, got %v", got)
+	}
+	if got := p.GetMetadataFloat("int64"); got != 5 {
+		t.Errorf("Expected 5, got %v", got)
+	}
+}
+
+func getAs[T any](p *Prompt) (any, error) {
+	return GetMetadataAs[T](p, "key")
+}
@@ -0,0 +1,195 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Parse reads text rendered by Prompt.String back into a prompt. Sections are
+// split at "---" lines, "Intro:" lines become intros (without the colon),
+// "- " bullets become instructions and labelled code fences become data
+// blocks of the fence's type. Untrusted data blocks are recognized by their
+// preamble and delimiters. Lines following a bullet continue that
+// instruction.
+//
+// Section flags and metadata are not part of the rendered text and are not
+// restored. An intro directly followed by an unlabelled data block reads as
+// the block's label; both render identically.
+func Parse(text string) (*Prompt, error) {
+	// \r\n line endings are accepted, but a \r inside data block content is
+	// kept as it is
+	lines := strings.Split(text, "\n")
+
+	// String starts every section with a newline. offset converts indexes
+	// into lines back to line numbers of text.
+	offset := 1
+	if len(lines) > 0 && strings.TrimSuffix(lines[0], "\r") == "" {
+		lines = lines[1:]
+		offset = 2
+	}
+
+	p := NewPrompt()
+	start := 0
+	fence, fenceLine := "", 0
+
+	for i, line := range lines {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case fence != "":
+			if line == fence {
+				fence = ""
+			}
+		case isFenceOpener(line):
+			fence, fenceLine = fenceOf(line), i+offset
+		case line == "---":
+			section, err := parseSection(lines[start:i], start+offset)
+			if err != nil {
+				return nil, err
+			}
+			p.AddSection(section)
+			start = i + 1
+		}
+	}
+
+	if fence != "" {
+		return nil, fmt.Errorf("line %d: unterminated code fence", fenceLine)
+	}
+
+	// tolerate a final section without a trailing separator
+	if rest := lines[start:]; strings.TrimSpace(strings.Join(rest, "\n")) != "" {
+		section, err := parseSection(rest, start+offset)
+		if err != nil {
+			return nil, err
+		}
+		p.AddSection(section)
+	}
+
+	return p, nil
+}
+
+// parseSection parses the lines of one section. firstLine is the line number
+// of lines[0] in the original text, used in error messages.
+func parseSection(raw []string, firstLine int) (Section, error) {
+	lines := make([]string, len(raw))
+	for i, line := range raw {
+		lines[i] = strings.TrimSuffix(line, "\r")
+	}
+
+	section := NewSection("")
+	if len(lines) == 1 && lines[0] == "" {
+		return section, nil
+	}
+
+	// isBlockStart reports whether lines[i] starts a data block
+	isBlockStart := func(i int) bool {
+		return isFenceOpener(lines[i]) || lines[i] == untrustedPreamble
+	}
+	// isLabel reports whether lines[i] labels the data block that follows
+	isLabel := func(i int) bool {
+		return strings.HasSuffix(lines[i], ":") && i+1 < len(lines) && isBlockStart(i+1)
+	}
+
+	i := 0
+	if len(lines) > 0 && !strings.HasPrefix(lines[0], "- ") && !isBlockStart(0) && !isLabel(0) {
+		section.Intro = strings.TrimSuffix(lines[0], ":")
+		i++
+	}
+
+	// inInstruction is true while lines may still continue the last instruction
+	inInstruction := false
+
+	for i < len(lines) {
+		line := lines[i]
+
+		switch {
+		case strings.HasPrefix(line, "- "):
+			section.AddInstruction(Instruction(line[2:]))
+			inInstruction = true
+			i++
+
+		case isBlockStart(i) || isLabel(i):
+			var label string
+			if !isBlockStart(i) {
+				label = strings.TrimSuffix(line, ":")
+				i++
+			}
+
+			// untrusted blocks are wrapped in a preamble and nonce delimiters
+			trust, endDelimiter := Trusted, ""
+			if lines[i] == untrustedPreamble {
+				var match []string
+				if i+2 < len(lines) {
+					match = beginPattern.FindStringSubmatch(lines[i+1])
+				}
+				if match == nil || !isFenceOpener(lines[i+2]) {
+					return Section{}, fmt.Errorf("line %d: untrusted data block without begin delimiter", firstLine+i)
+				}
+				trust, endDelimiter = Untrusted, "<<<END UNTRUSTED DATA "+match[1]+">>>"
+				i += 2
+			}
+
+			fence := fenceOf(lines[i])
+			blockType := strings.TrimPrefix(lines[i], fence)
+			end := i + 1
+			for end < len(lines) && lines[end] != fence {
+				end++
+			}
+			if end == len(lines) {
+				return Section{}, fmt.Errorf("line %d: unterminated code fence", firstLine+i)
+			}
+
+			section.DataBlocks = append(section.DataBlocks, DataBlock{
+				Label:   label,
+				Content: strings.Join(raw[i+1:end], "\n"),
+				Type:    blockType,
+				Trust:   trust,
+			})
+			inInstruction = false
+			i = end + 1
+
+			if trust == Untrusted {
+				if i == len(lines) || lines[i] != endDelimiter {
+					return Section{}, fmt.Errorf("line %d: expected %q", firstLine+i, endDelimiter)
+				}
+				i++
+			}
+
+		case line == "":
+			// blank lines separate data blocks, or continue a multi-line instruction
+			next := i
+			for next < len(lines) && lines[next] == "" {
+				next++
+			}
+			if inInstruction && next < len(lines) && !isBlockStart(next) && !isLabel(next) && !strings.HasPrefix(lines[next], "- ") {
+				last := &section.Instructions[len(section.Instructions)-1]
+				*last += Instruction(strings.Repeat("\n", next-i))
+			}
+			i = next
+
+		case inInstruction:
+			last := &section.Instructions[len(section.Instructions)-1]
+			*last += Instruction("\n" + line)
+			i++
+
+		default:
+			return Section{}, fmt.Errorf("line %d: unexpected text %q", firstLine+i, line)
+		}
+	}
+
+	return section, nil
+}
+
+// isFenceOpener reports whether line opens a code fence of three or more backticks
+func isFenceOpener(line string) bool {
+	fence := fenceOf(line)
+	return len(fence) >= 3 && !strings.Contains(line[len(fence):], "`")
+}
+
+// fenceOf returns the run of backticks line starts with
+func fenceOf(line string) string {
+	n := 0
+	for n < len(line) && line[n] == '`' {
+		n++
+	}
+	return line[:n]
+}
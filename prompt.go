@@ -1,8 +1,27 @@
 package prompt
 
+import "io"
+
+// Well-known metadata keys read by ToMessages, the provider encoders and
+// GenerationConfig
+const (
+	SystemContextKey = "system_context"
+	ModelKey         = "model"
+	TemperatureKey   = "temperature"
+	MaxTokensKey     = "max_tokens"
+	TopPKey          = "top_p"
+
+	LanguageKey                 = "lang_iso_6391"
+	MinRequiredWordsKey         = "output_min_required_words"
+	ContinuationInstructionsKey = "continuation_instructions"
+	HighQualityKey              = "model_high_quality"
+	LargeTokensKey              = "model_large_tokens"
+)
+
 type Prompt struct {
-	Sections []Section
-	metadata map[string]any
+	Sections  []Section
+	metadata  map[string]any
+	tokenizer Tokenizer
 }
 
 func NewPrompt() *Prompt {
@@ -40,16 +59,18 @@ func (p *Prompt) GetMetadataString(key string) string {
 	return ""
 }
 
-// GetMetadataInt retrieves a metadata value as int
+// GetMetadataInt retrieves a metadata value as int. Other integer types and
+// whole floats (as decoded from JSON) are converted; see GetMetadataAs.
 func (p *Prompt) GetMetadataInt(key string) int {
-	value, exists := p.GetMetadata(key)
-	if !exists {
-		return 0
-	}
-	if i, ok := value.(int); ok {
-		return i
-	}
-	return 0
+	i, _ := GetMetadataAs[int](p, key)
+	return i
+}
+
+// GetMetadataFloat retrieves a metadata value as float64. Any integer or float
+// type is converted; see GetMetadataAs.
+func (p *Prompt) GetMetadataFloat(key string) float64 {
+	f, _ := GetMetadataAs[float64](p, key)
+	return f
 }
 
 // GetMetadataBool retrieves a metadata value as bool
@@ -98,32 +119,27 @@ func (p *Prompt) AddSections(sections []Section) {
 }
 
 func (p *Prompt) String() string {
-	var output string
-
-	for _, section := range p.Sections {
-		output += "\n" + section.String() + "\n---"
-	}
-
+	output, _ := p.Render(TextRenderer{})
 	return output
 }
 
-func (p *Prompt) WordCount() int {
-	var count int
-
-	for _, section := range p.Sections {
-		count += section.WordsCount()
-	}
+// WriteTo streams the prompt as String renders it to w without building the
+// whole text in memory
+func (p *Prompt) WriteTo(w io.Writer) (int64, error) {
+	return Sections(p.Sections).WriteTo(w)
+}
 
-	return count
+// WordCount returns the number of words in the rendered prompt, including
+// intros, data blocks and separators (see WordBreakdown)
+func (p *Prompt) WordCount() int {
+	return p.WordBreakdown().Total()
 }
 
-// TokenCount - returns the number of tokens in the prompt derived from word count
+// TokenCount - returns the number of tokens in the rendered prompt. Without a
+// tokenizer (see SetTokenizer) it is derived from the word count.
 func (p *Prompt) TokenCount() int {
-	var count int
-
-	for _, section := range p.Sections {
-		count += int(float64(section.WordsCount()) * 1.4)
+	if p.tokenizer != nil {
+		return p.tokenizer.CountTokens(p.String())
 	}
-
-	return count
+	return int(float64(p.WordCount()) * heuristicTokensPerWord)
 }
@@ -1,11 +1,33 @@
 package prompt
 
-import "testing"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// newTestPrompt returns a small prompt with an instruction list, a data block
+// and a second section, shared by tests that only render or compare prompts
+func newTestPrompt() *Prompt {
+	p := NewPrompt()
+
+	task := NewSection("Task")
+	task.AddInstruction("Summarize the input")
+	task.AddInstruction("Keep it short")
+	task.AddRawJSON("Input", `{"id": 1}`)
+
+	p.AddSection(task)
+	p.AddSection(Section{Intro: "Style:", Instructions: []Instruction{"Be friendly"}})
+
+	return p
+}
 
 func TestPrompt(t *testing.T) {
 	p := NewPrompt()
-	p.AddSection(Section{"intro1", []Instruction{"test1", "test2"}, []DataBlock{}})
-	p.AddSection(Section{"intro2", []Instruction{"test3", "test4"}, []DataBlock{}})
+	p.AddSection(Section{Intro: "intro1", Instructions: []Instruction{"test1", "test2"}, DataBlocks: []DataBlock{}})
+	p.AddSection(Section{Intro: "intro2", Instructions: []Instruction{"test3", "test4"}, DataBlocks: []DataBlock{}})
 
 	expected := "\nintro1:\n- test1\n- test2\n---\nintro2:\n- test3\n- test4\n---"
 	actual := p.String()
@@ -17,10 +39,11 @@ func TestPrompt(t *testing.T) {
 
 func TestPromptWordsCount(t *testing.T) {
 	p := NewPrompt()
-	p.AddSection(Section{"intro1", []Instruction{"test1", "test2"}, []DataBlock{}})
-	p.AddSection(Section{"intro2", []Instruction{"test3", "test4"}, []DataBlock{}})
+	p.AddSection(Section{Intro: "intro1", Instructions: []Instruction{"test1", "test2"}, DataBlocks: []DataBlock{}})
+	p.AddSection(Section{Intro: "intro2", Instructions: []Instruction{"test3", "test4"}, DataBlocks: []DataBlock{}})
 
-	expected := 4
+	// per section: intro, 2 bullets, 2 instructions and the "---" separator
+	expected := 12
 	actual := p.WordCount()
 
 	if actual != expected {
@@ -30,10 +53,11 @@ func TestPromptWordsCount(t *testing.T) {
 
 func TestPromptTokenCount(t *testing.T) {
 	p := NewPrompt()
-	p.AddSection(Section{"intro1", []Instruction{"test1", "test2"}, []DataBlock{}})
-	p.AddSection(Section{"intro2", []Instruction{"test3", "test4", "test5"}, []DataBlock{}})
+	p.AddSection(Section{Intro: "intro1", Instructions: []Instruction{"test1", "test2"}, DataBlocks: []DataBlock{}})
+	p.AddSection(Section{Intro: "intro2", Instructions: []Instruction{"test3", "test4", "test5"}, DataBlocks: []DataBlock{}})
 
-	expected := 6
+	// 14 words (intros, bullets, instructions, separators) * 1.4
+	expected := 19
 	actual := p.TokenCount()
 
 	if actual != expected {
@@ -58,8 +82,8 @@ func TestPromptAddSections(t *testing.T) {
 	p := NewPrompt()
 
 	sections := []Section{
-		{"intro1", []Instruction{"test1"}, []DataBlock{}},
-		{"intro2", []Instruction{"test2"}, []DataBlock{}},
+		{Intro: "intro1", Instructions: []Instruction{"test1"}, DataBlocks: []DataBlock{}},
+		{Intro: "intro2", Instructions: []Instruction{"test2"}, DataBlocks: []DataBlock{}},
 	}
 
 	p.AddSections(sections)
@@ -238,22 +262,22 @@ func TestSectionString(t *testing.T) {
 	}{
 		{
 			name:     "Section with intro and instructions",
-			section:  Section{"Test", []Instruction{"inst1", "inst2"}, []DataBlock{}},
+			section:  Section{Intro: "Test", Instructions: []Instruction{"inst1", "inst2"}, DataBlocks: []DataBlock{}},
 			expected: "Test:\n- inst1\n- inst2",
 		},
 		{
 			name:     "Section with intro ending in colon",
-			section:  Section{"Test:", []Instruction{"inst1"}, []DataBlock{}},
+			section:  Section{Intro: "Test:", Instructions: []Instruction{"inst1"}, DataBlocks: []DataBlock{}},
 			expected: "Test:\n- inst1",
 		},
 		{
 			name:     "Section without intro",
-			section:  Section{"", []Instruction{"inst1", "inst2"}, []DataBlock{}},
+			section:  Section{Intro: "", Instructions: []Instruction{"inst1", "inst2"}, DataBlocks: []DataBlock{}},
 			expected: "- inst1\n- inst2",
 		},
 		{
 			name:     "Empty section",
-			section:  Section{"", []Instruction{}, []DataBlock{}},
+			section:  Section{Intro: "", Instructions: []Instruction{}, DataBlocks: []DataBlock{}},
 			expected: "",
 		},
 	}
@@ -270,7 +294,7 @@ func TestSectionString(t *testing.T) {
 
 func TestSectionWordsCount(t *testing.T) {
 	section := Section{
-		Intro: "ignored",
+		Intro: "Intro",
 		Instructions: []Instruction{
 			"one two three",
 			"four five",
@@ -278,7 +302,8 @@ func TestSectionWordsCount(t *testing.T) {
 		DataBlocks: []DataBlock{},
 	}
 
-	expected := 5
+	// intro + 5 instruction words + 2 bullets
+	expected := 8
 	actual := section.WordsCount()
 
 	if actual != expected {
@@ -288,8 +313,8 @@ func TestSectionWordsCount(t *testing.T) {
 
 func TestSectionsString(t *testing.T) {
 	sections := Sections{
-		{"intro1", []Instruction{"test1"}, []DataBlock{}},
-		{"intro2", []Instruction{"test2"}, []DataBlock{}},
+		{Intro: "intro1", Instructions: []Instruction{"test1"}, DataBlocks: []DataBlock{}},
+		{Intro: "intro2", Instructions: []Instruction{"test2"}, DataBlocks: []DataBlock{}},
 	}
 
 	expected := "\nintro1:\n- test1\n---\nintro2:\n- test2\n---"
@@ -302,11 +327,11 @@ func TestSectionsString(t *testing.T) {
 
 func TestWordsCount(t *testing.T) {
 	sections := []Section{
-		{"intro1", []Instruction{"one two"}, []DataBlock{}},
-		{"intro2", []Instruction{"three four five"}, []DataBlock{}},
+		{Intro: "intro1", Instructions: []Instruction{"one two"}, DataBlocks: []DataBlock{}},
+		{Intro: "intro2", Instructions: []Instruction{"three four five"}, DataBlocks: []DataBlock{}},
 	}
 
-	expected := 5
+	expected := 9
 	actual := WordsCount(sections)
 
 	if actual != expected {
@@ -391,6 +416,63 @@ func TestInstructionWordCount(t *testing.T) {
 	}
 }
 
+func TestPromptWriteTo(t *testing.T) {
+	p := newRAGPrompt(3)
+	p.Sections[0].PinToEnd = true
+	p.AddSection(Section{Instructions: []Instruction{"no intro"}})
+	p.AddSection(NewSection(""))
+
+	var b strings.Builder
+	n, err := p.WriteTo(&b)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if b.String() != p.String() {
+		t.Errorf("Expected WriteTo to match String:\n%s\nGot:\n%s", p.String(), b.String())
+	}
+	if n != int64(b.Len()) {
+		t.Errorf("Expected %d bytes written, got %d", b.Len(), n)
+	}
+
+	b.Reset()
+	if _, err := Sections(p.Sections).WriteTo(&b); err != nil || b.String() != Sections(p.Sections).String() {
+		t.Errorf("Expected Sections.WriteTo to match String, got %q (err %v)", b.String(), err)
+	}
+
+	section := p.Sections[1]
+	b.Reset()
+	if _, err := section.WriteTo(&b); err != nil || b.String() != section.String() {
+		t.Errorf("Expected Section.WriteTo to match String, got %q (err %v)", b.String(), err)
+	}
+}
+
+// failingWriter accepts limit bytes and then fails
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	if len(b) > w.limit {
+		n := w.limit
+		w.limit = 0
+		return n, errors.New("disk full")
+	}
+	w.limit -= len(b)
+	return len(b), nil
+}
+
+func TestPromptWriteToError(t *testing.T) {
+	p := newRAGPrompt(2)
+
+	n, err := p.WriteTo(&failingWriter{limit: 100})
+	if err == nil || err.Error() != "disk full" {
+		t.Errorf("Expected write error, got %v", err)
+	}
+	if n != 100 {
+		t.Errorf("Expected 100 bytes written, got %d", n)
+	}
+}
+
 // Benchmark tests
 
 func BenchmarkPromptString(b *testing.B) {
@@ -403,12 +485,53 @@ func BenchmarkPromptString(b *testing.B) {
 		p.AddSection(section)
 	}
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_ = p.String()
 	}
 }
 
+// newRAGPrompt builds a retrieval-augmented prompt with docs documents of
+// roughly 2 KB each
+func newRAGPrompt(docs int) *Prompt {
+	p := NewPrompt()
+
+	task := NewSection("Answer the question using only the documents below")
+	task.AddInstruction("Cite the document number for every claim")
+	task.AddInstruction("Say so if the documents do not contain the answer")
+	p.AddSection(task)
+
+	content := strings.Repeat("Retrieved passage text with several words per line.\n", 40)
+	for i := 0; i < docs; i++ {
+		section := NewSection(fmt.Sprintf("Document %d", i+1))
+		section.AddRawJSON("Source", fmt.Sprintf(`{"id": %d, "score": 0.87}`, i))
+		section.AddRawHTML("Content", content)
+		p.AddSection(section)
+	}
+	return p
+}
+
+func BenchmarkPromptStringRAG(b *testing.B) {
+	p := newRAGPrompt(500)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = p.String()
+	}
+}
+
+func BenchmarkPromptWriteToRAG(b *testing.B) {
+	p := newRAGPrompt(500)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = p.WriteTo(io.Discard)
+	}
+}
+
 func BenchmarkPromptWordCount(b *testing.B) {
 	p := NewPrompt()
 	for i := 0; i < 10; i++ {
@@ -0,0 +1,203 @@
+package promptfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	jsonUnknownField = regexp.MustCompile(`^json: unknown field "(.*)"$`)
+	jsonFieldIndex   = regexp.MustCompile(`\.(\d+)`)
+)
+
+func decodeJSON(data []byte) (fileDef, locator, error) {
+	var def fileDef
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+
+	locate := func(path []any) (int, int) {
+		offset, ok := jsonOffset(data, path)
+		if !ok {
+			return 0, 0
+		}
+		return lineColumn(data, offset)
+	}
+
+	if err := dec.Decode(&def); err != nil {
+		var (
+			syntaxErr *json.SyntaxError
+			typeErr   *json.UnmarshalTypeError
+		)
+		switch {
+		case errors.As(err, &syntaxErr):
+			line, column := lineColumn(data, int(syntaxErr.Offset))
+			return def, nil, &Error{Line: line, Column: column, Msg: syntaxErr.Error()}
+		case errors.As(err, &typeErr):
+			line, column := lineColumn(data, int(typeErr.Offset))
+			return def, nil, &Error{
+				Line:   line,
+				Column: column,
+				Path:   jsonFieldIndex.ReplaceAllString(typeErr.Field, "[$1]"),
+				Msg:    "cannot use JSON " + typeErr.Value + " as " + typeErr.Type.String(),
+			}
+		case errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF):
+			line, column := lineColumn(data, len(data))
+			return def, nil, &Error{Line: line, Column: column, Msg: "unexpected end of file"}
+		}
+
+		if m := jsonUnknownField.FindStringSubmatch(err.Error()); m != nil {
+			line, column := 0, 0
+			if offset, ok := jsonKeyOffset(data, m[1]); ok {
+				line, column = lineColumn(data, offset)
+			}
+			return def, nil, &Error{Line: line, Column: column, Msg: "unknown field " + strconv.Quote(m[1])}
+		}
+		return def, nil, &Error{Msg: err.Error()}
+	}
+
+	if _, err := dec.Token(); err != io.EOF {
+		line, column := lineColumn(data, int(dec.InputOffset()))
+		return def, nil, &Error{Line: line, Column: column, Msg: "unexpected data after the prompt definition"}
+	}
+
+	return def, locate, nil
+}
+
+// normalizeNumber turns a json.Number into an int or float64
+func normalizeNumber(value any) any {
+	n, ok := value.(json.Number)
+	if !ok {
+		return value
+	}
+	if i, err := strconv.Atoi(n.String()); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
+
+// jsonOffset returns the byte offset of the value at path
+func jsonOffset(data []byte, path []any) (int, bool) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if !seekJSON(dec, path) {
+		return 0, false
+	}
+	offset := int(dec.InputOffset())
+	for offset < len(data) && strings.ContainsRune(" \t\r\n:,", rune(data[offset])) {
+		offset++
+	}
+	return offset, true
+}
+
+// seekJSON consumes tokens until the value at path is next
+func seekJSON(dec *json.Decoder, path []any) bool {
+	if len(path) == 0 {
+		return true
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return false
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return false
+			}
+			if key == path[0] {
+				return seekJSON(dec, path[1:])
+			}
+			if !skipJSON(dec) {
+				return false
+			}
+		}
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if i == path[0] {
+				return seekJSON(dec, path[1:])
+			}
+			if !skipJSON(dec) {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// skipJSON consumes the next value
+func skipJSON(dec *json.Decoder) bool {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return true
+		}
+	}
+}
+
+// jsonKeyOffset returns the offset of the first object key named key
+func jsonKeyOffset(data []byte, key string) (int, bool) {
+	type frame struct{ object, expectKey bool }
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var stack []frame
+
+	// valueDone marks the end of a value inside an object, so a key follows
+	valueDone := func() {
+		if len(stack) > 0 && stack[len(stack)-1].object {
+			stack[len(stack)-1].expectKey = true
+		}
+	}
+
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, false
+		}
+
+		switch tok {
+		case json.Delim('{'):
+			stack = append(stack, frame{object: true, expectKey: true})
+		case json.Delim('['):
+			stack = append(stack, frame{})
+		case json.Delim('}'), json.Delim(']'):
+			stack = stack[:len(stack)-1]
+			valueDone()
+		default:
+			if len(stack) > 0 && stack[len(stack)-1].expectKey {
+				if tok == key {
+					offset := int(start)
+					for offset < len(data) && data[offset] != '"' {
+						offset++
+					}
+					return offset, true
+				}
+				stack[len(stack)-1].expectKey = false
+				continue
+			}
+			valueDone()
+		}
+	}
+}
@@ -0,0 +1,260 @@
+// Package promptfile loads prompts from declarative JSON, YAML or TOML files,
+// so wording changes do not require a rebuild. A file has optional metadata
+// and a list of sections:
+//
+//	metadata:
+//	  model: gpt-4o
+//	  temperature: 0.3
+//	sections:
+//	  - intro: Summarize the ticket
+//	    required: true
+//	    instructions:
+//	      - Keep it under 100 words
+//	    data_blocks:
+//	      - label: Ticket
+//	        type: json
+//	        content: '{"id": 42}'
+//
+// Errors are returned as *Error and carry the line of the offending value.
+package promptfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sklinkert/prompt"
+)
+
+// Format is the syntax of a prompt definition file
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TOML Format = "toml"
+)
+
+// Error describes a problem in a prompt definition file
+type Error struct {
+	File   string // file name, empty for Parse
+	Line   int    // 1-based line, 0 if unknown
+	Column int    // 1-based column, 0 if unknown
+	Path   string // location in the document, e.g. sections[1].data_blocks[0].type
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File + ":")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "%d:", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, "%d:", e.Column)
+		}
+	}
+	if b.Len() > 0 {
+		b.WriteString(" ")
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path + ": ")
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+type fileDef struct {
+	Metadata map[string]any `json:"metadata" yaml:"metadata" toml:"metadata"`
+	Sections []sectionDef   `json:"sections" yaml:"sections" toml:"sections"`
+}
+
+type sectionDef struct {
+	Intro        string         `json:"intro" yaml:"intro" toml:"intro"`
+	Instructions []string       `json:"instructions" yaml:"instructions" toml:"instructions"`
+	DataBlocks   []dataBlockDef `json:"data_blocks" yaml:"data_blocks" toml:"data_blocks"`
+	Priority     int            `json:"priority" yaml:"priority" toml:"priority"`
+	Required     bool           `json:"required" yaml:"required" toml:"required"`
+	PinToEnd     bool           `json:"pin_to_end" yaml:"pin_to_end" toml:"pin_to_end"`
+}
+
+type dataBlockDef struct {
+	Label   string       `json:"label" yaml:"label" toml:"label"`
+	Type    string       `json:"type" yaml:"type" toml:"type"`
+	Content string       `json:"content" yaml:"content" toml:"content"`
+	Trust   prompt.Trust `json:"trust" yaml:"trust" toml:"trust"`
+}
+
+// locator finds the line and column of the value at path
+type locator func(path []any) (line, column int)
+
+// Load reads a prompt definition file. The format is derived from the file
+// extension (.json, .yaml, .yml or .toml).
+func Load(path string) (*prompt.Prompt, error) {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt file: %w", err)
+	}
+
+	p, err := Parse(data, format)
+	if e, ok := err.(*Error); ok {
+		e.File = path
+	}
+	return p, err
+}
+
+// FormatFromPath returns the format matching the extension of path
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON, nil
+	case ".yaml", ".yml":
+		return YAML, nil
+	case ".toml":
+		return TOML, nil
+	}
+	return "", fmt.Errorf("unsupported prompt file extension %q", filepath.Ext(path))
+}
+
+// Parse decodes a prompt definition in the given format
+func Parse(data []byte, format Format) (*prompt.Prompt, error) {
+	var (
+		def    fileDef
+		locate locator
+		err    error
+	)
+
+	switch format {
+	case JSON:
+		def, locate, err = decodeJSON(data)
+	case YAML:
+		def, locate, err = decodeYAML(data)
+	case TOML:
+		def, locate, err = decodeTOML(data)
+	default:
+		return nil, fmt.Errorf("unsupported prompt file format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return build(def, locate)
+}
+
+func build(def fileDef, locate locator) (*prompt.Prompt, error) {
+	p := prompt.NewPrompt()
+
+	for key, value := range def.Metadata {
+		p.SetMetadata(key, normalize(value))
+	}
+
+	for i, sd := range def.Sections {
+		section := prompt.NewSection(sd.Intro)
+		section.Priority = sd.Priority
+		section.Required = sd.Required
+		section.PinToEnd = sd.PinToEnd
+
+		for _, instruction := range sd.Instructions {
+			section.AddInstruction(prompt.NewInstruction(instruction))
+		}
+
+		for j, bd := range sd.DataBlocks {
+			switch bd.Type {
+			case "json":
+				section.AddRawJSON(bd.Label, bd.Content)
+			case "xml":
+				section.AddRawXML(bd.Label, bd.Content)
+			case "html":
+				section.AddRawHTML(bd.Label, bd.Content)
+			default:
+				path := []any{"sections", i, "data_blocks", j, "type"}
+				if bd.Type == "" {
+					path = path[:4]
+				}
+				line, column := locate(path)
+				return nil, &Error{
+					Line:   line,
+					Column: column,
+					Path:   formatPath(path),
+					Msg:    fmt.Sprintf("unsupported data block type %q (want json, xml or html)", bd.Type),
+				}
+			}
+			section.DataBlocks[len(section.DataBlocks)-1].Trust = bd.Trust
+		}
+
+		p.AddSection(section)
+	}
+
+	return p, nil
+}
+
+// normalize converts decoded numbers to int where they are integral and to
+// float64 otherwise, so metadata looks the same regardless of file format
+func normalize(value any) any {
+	switch v := value.(type) {
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	case float64:
+		return v
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalize(item)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = normalize(item)
+		}
+		return v
+	case []map[string]any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = normalize(item)
+		}
+		return out
+	}
+	return normalizeNumber(value)
+}
+
+// formatPath renders a path like sections[1].data_blocks[0].type
+func formatPath(path []any) string {
+	var b strings.Builder
+	for _, elem := range path {
+		switch e := elem.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", e)
+		case string:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(e)
+		}
+	}
+	return b.String()
+}
+
+// lineColumn converts a byte offset into a 1-based line and column
+func lineColumn(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line, column := 1, 1
+	for _, c := range data[:offset] {
+		if c == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
@@ -0,0 +1,195 @@
+package promptfile
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sklinkert/prompt"
+)
+
+func TestLoad(t *testing.T) {
+	expected := "\nSummarize the ticket:\n- Keep it under 100 words\n- Mention the customer id\n\n" +
+		"Ticket:\n```json\n{\"id\": 42, \"subject\": \"Login fails\"}\n```\n---" +
+		"\nOutput format:\n- Answer in Markdown\n---"
+
+	for _, name := range []string{"prompt.yaml", "prompt.json", "prompt.toml"} {
+		t.Run(name, func(t *testing.T) {
+			p, err := Load(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if p.String() != expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", expected, p.String())
+			}
+
+			if !p.Sections[0].Required || !p.Sections[1].PinToEnd || p.Sections[1].Priority != 10 {
+				t.Errorf("Unexpected section flags %+v", p.Sections)
+			}
+
+			if p.GetMetadataString(prompt.ModelKey) != "gpt-4o" || p.GetMetadataString(prompt.SystemContextKey) != "You are a support agent" {
+				t.Errorf("Unexpected metadata %v", p.GetAllMetadata())
+			}
+			if p.GetMetadataInt(prompt.MaxTokensKey) != 500 {
+				t.Errorf("Expected max_tokens to be an int, got %#v", p.GetAllMetadata()[prompt.MaxTokensKey])
+			}
+			if temperature, _ := p.GetMetadata(prompt.TemperatureKey); temperature != 0.3 {
+				t.Errorf("Expected temperature 0.3, got %#v", temperature)
+			}
+		})
+	}
+}
+
+func TestParseTrust(t *testing.T) {
+	inputs := map[Format]string{
+		JSON: `{"sections": [{"intro": "x", "data_blocks": [{"type": "html", "content": "<p>hi</p>", "trust": "untrusted"}]}]}`,
+		YAML: "sections:\n  - intro: x\n    data_blocks:\n      - type: html\n        content: <p>hi</p>\n        trust: untrusted\n",
+		TOML: "[[sections]]\nintro = \"x\"\n\n[[sections.data_blocks]]\ntype = \"html\"\ncontent = \"<p>hi</p>\"\ntrust = \"untrusted\"\n",
+	}
+
+	for format, input := range inputs {
+		t.Run(string(format), func(t *testing.T) {
+			p, err := Parse([]byte(input), format)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if trust := p.Sections[0].DataBlocks[0].Trust; trust != prompt.Untrusted {
+				t.Errorf("Expected untrusted data block, got %v", trust)
+			}
+		})
+	}
+
+	_, err := Parse([]byte("sections:\n  - data_blocks:\n      - type: html\n        trust: maybe\n"), YAML)
+	if err == nil || !strings.Contains(err.Error(), "invalid trust level") {
+		t.Errorf("Expected invalid trust level error, got %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   Format
+		input    string
+		line     int
+		contains string
+	}{
+		{
+			name:     "json syntax",
+			format:   JSON,
+			input:    "{\n  \"sections\": [\n    {\"intro\": \"x\",}\n  ]\n}",
+			line:     3,
+			contains: "invalid character",
+		},
+		{
+			name:     "json type",
+			format:   JSON,
+			input:    "{\n  \"sections\": [\n    {\"intro\": 5}\n  ]\n}",
+			line:     3,
+			contains: "sections[0].intro",
+		},
+		{
+			name:     "json unknown field",
+			format:   JSON,
+			input:    "{\n  \"sections\": [\n    {\"intro\": \"x\",\n     \"instruction\": []}\n  ]\n}",
+			line:     4,
+			contains: `unknown field "instruction"`,
+		},
+		{
+			name:     "json data block type",
+			format:   JSON,
+			input:    "{\"sections\": [\n {\"data_blocks\": [\n  {\"label\": \"a\", \"type\": \"csv\", \"content\": \"x\"}\n ]}\n]}",
+			line:     3,
+			contains: `sections[0].data_blocks[0].type: unsupported data block type "csv"`,
+		},
+		{
+			name:     "yaml syntax",
+			format:   YAML,
+			input:    "sections:\n  - intro: x\n    instructions: [a,\n",
+			line:     3,
+			contains: "did not find expected node content",
+		},
+		{
+			name:     "yaml unknown field",
+			format:   YAML,
+			input:    "sections:\n  - intro: x\n    instruction:\n      - y\n",
+			line:     3,
+			contains: "field instruction not found",
+		},
+		{
+			name:     "yaml data block type",
+			format:   YAML,
+			input:    "sections:\n  - intro: x\n    data_blocks:\n      - label: a\n        type: csv\n",
+			line:     5,
+			contains: `unsupported data block type "csv"`,
+		},
+		{
+			name:     "yaml missing data block type",
+			format:   YAML,
+			input:    "sections:\n  - intro: x\n    data_blocks:\n      - label: a\n        content: b\n",
+			line:     4,
+			contains: `sections[0].data_blocks[0]: unsupported data block type ""`,
+		},
+		{
+			name:     "toml syntax",
+			format:   TOML,
+			input:    "[[sections]]\nintro = \"x\"\ninstructions = [\n",
+			line:     3,
+			contains: "unexpected EOF",
+		},
+		{
+			name:     "toml unknown field",
+			format:   TOML,
+			input:    "[[sections]]\nintro = \"x\"\ninstruction = \"y\"\n",
+			line:     3,
+			contains: `unknown field "instruction"`,
+		},
+		{
+			name:     "toml data block type",
+			format:   TOML,
+			input:    "[[sections]]\nintro = \"a\"\n\n[[sections]]\nintro = \"b\"\n\n[[sections.data_blocks]]\nlabel = \"x\"\ntype = \"csv\"\n",
+			line:     9,
+			contains: `sections[1].data_blocks[0].type`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input), tt.format)
+
+			var fileErr *Error
+			if !errors.As(err, &fileErr) {
+				t.Fatalf("Expected *Error, got %v", err)
+			}
+			if fileErr.Line != tt.line {
+				t.Errorf("Expected line %d, got %d (%v)", tt.line, fileErr.Line, err)
+			}
+			if !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected error to contain %q, got %q", tt.contains, err.Error())
+			}
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load("prompt.txt"); err == nil {
+		t.Error("Expected error for unsupported extension")
+	}
+	if _, err := Load(filepath.Join("testdata", "missing.yaml")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if _, err := Parse([]byte("{}"), Format("ini")); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	err := &Error{File: "p.yaml", Line: 3, Column: 7, Path: "sections[0].intro", Msg: "bad"}
+	if err.Error() != "p.yaml:3:7: sections[0].intro: bad" {
+		t.Errorf("Unexpected error string %q", err.Error())
+	}
+	if (&Error{Msg: "bad"}).Error() != "bad" {
+		t.Errorf("Unexpected error string %q", (&Error{Msg: "bad"}).Error())
+	}
+}
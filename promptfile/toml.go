@@ -0,0 +1,120 @@
+package promptfile
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+var tomlLine = regexp.MustCompile(`^toml: line (\d+)(?: \(last key "[^"]*"\))?: (.*)$`)
+
+func decodeTOML(data []byte) (fileDef, locator, error) {
+	var def fileDef
+
+	md, err := toml.Decode(string(data), &def)
+	if err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return def, nil, &Error{
+				Line:   parseErr.Position.Line,
+				Column: parseErr.Position.Col,
+				Path:   parseErr.LastKey,
+				Msg:    parseErr.Message,
+			}
+		}
+		if m := tomlLine.FindStringSubmatch(err.Error()); m != nil {
+			var line int
+			fmt.Sscan(m[1], &line)
+			return def, nil, &Error{Line: line, Msg: m[2]}
+		}
+		return def, nil, &Error{Msg: strings.TrimPrefix(err.Error(), "toml: ")}
+	}
+
+	lines := strings.Split(string(data), "\n")
+
+	for _, key := range md.Undecoded() {
+		// metadata is free-form and decoded into a map
+		if len(key) > 0 && key[0] == "metadata" {
+			continue
+		}
+		name := key[len(key)-1]
+		return def, nil, &Error{
+			Line: tomlKeyLine(lines, name, 0, len(lines)),
+			Path: key.String(),
+			Msg:  fmt.Sprintf("unknown field %q", name),
+		}
+	}
+
+	locate := func(path []any) (int, int) {
+		return tomlLocate(lines, path), 0
+	}
+
+	return def, locate, nil
+}
+
+// tomlLocate finds the line of a value written with [[sections]] and
+// [[sections.data_blocks]] array tables. Other layouts, such as inline
+// tables, are not located and return 0.
+func tomlLocate(lines []string, path []any) int {
+	start, end := 0, len(lines)
+	header := ""
+
+	for len(path) >= 2 {
+		name, ok := path[0].(string)
+		index, isIndex := path[1].(int)
+		if !ok || !isIndex {
+			break
+		}
+		header = strings.TrimPrefix(header+"."+name, ".")
+
+		found := -1
+		for i, n := start, 0; i < end; i++ {
+			if strings.TrimSpace(lines[i]) == "[["+header+"]]" {
+				if n == index {
+					found = i
+					break
+				}
+				n++
+			}
+		}
+		if found < 0 {
+			return 0
+		}
+
+		start = found + 1
+		for i := start; i < end; i++ {
+			t := strings.TrimSpace(lines[i])
+			if t == "[["+header+"]]" || (strings.HasPrefix(t, "[") && !strings.HasPrefix(t, "[["+header+".") && !strings.HasPrefix(t, "["+header+".")) {
+				end = i
+				break
+			}
+		}
+		path = path[2:]
+
+		if len(path) == 0 {
+			return found + 1
+		}
+	}
+
+	if len(path) == 1 {
+		if name, ok := path[0].(string); ok {
+			return tomlKeyLine(lines, name, start, end)
+		}
+	}
+	return 0
+}
+
+var tomlKey = regexp.MustCompile(`^\s*([A-Za-z0-9_-]+)\s*=`)
+
+// tomlKeyLine returns the 1-based line of the first "name = " in lines[start:end]
+func tomlKeyLine(lines []string, name string, start, end int) int {
+	for i := start; i < end; i++ {
+		if m := tomlKey.FindStringSubmatch(lines[i]); m != nil && m[1] == name {
+			return i + 1
+		}
+	}
+	return 0
+}
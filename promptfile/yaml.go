@@ -0,0 +1,80 @@
+package promptfile
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+func decodeYAML(data []byte) (fileDef, locator, error) {
+	var def fileDef
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&def); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+			return def, nil, yamlError(typeErr.Errors[0])
+		}
+		return def, nil, yamlError(err.Error())
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return def, nil, yamlError(err.Error())
+	}
+
+	locate := func(path []any) (int, int) {
+		node := seekYAML(&root, path)
+		if node == nil {
+			return 0, 0
+		}
+		return node.Line, node.Column
+	}
+
+	return def, locate, nil
+}
+
+// yamlError extracts the line number from a yaml.v3 error message
+func yamlError(msg string) *Error {
+	m := yamlLine.FindStringSubmatch(msg)
+	if m == nil {
+		return &Error{Msg: strings.TrimPrefix(msg, "yaml: ")}
+	}
+	line, _ := strconv.Atoi(m[1])
+	return &Error{Line: line, Msg: m[2]}
+}
+
+// seekYAML returns the node at path below node
+func seekYAML(node *yaml.Node, path []any) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		return seekYAML(node.Content[0], path)
+	}
+	if len(path) == 0 {
+		return node
+	}
+
+	switch key := path[0].(type) {
+	case string:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				return seekYAML(node.Content[i+1], path[1:])
+			}
+		}
+	case int:
+		if node.Kind == yaml.SequenceNode && key < len(node.Content) {
+			return seekYAML(node.Content[key], path[1:])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sklinkert/prompt"
+)
+
+// Anthropic encodes requests for the Anthropic Messages API. System messages
+// are joined into the top-level "system" field.
+type Anthropic struct {
+	// Renderer formats message content (default prompt.TextRenderer)
+	Renderer prompt.Renderer
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature *float64           `json:"temperature,omitempty"`
+	TopP        *float64           `json:"top_p,omitempty"`
+}
+
+func (e Anthropic) Encode(p *prompt.Prompt) ([]byte, error) {
+	return encodePrompt(e, p)
+}
+
+func (e Anthropic) EncodeConversation(c *prompt.Conversation, params Params) ([]byte, error) {
+	if params.Model == "" {
+		return nil, errors.New("anthropic: model is required")
+	}
+	if params.MaxTokens == 0 {
+		return nil, errors.New("anthropic: max_tokens is required")
+	}
+
+	req := anthropicRequest{
+		Model:       params.Model,
+		Messages:    make([]anthropicMessage, 0, len(c.Messages)),
+		MaxTokens:   params.MaxTokens,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+	}
+
+	var system []string
+	for _, msg := range c.Messages {
+		text, err := renderMessage(msg, e.Renderer)
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg.Role {
+		case prompt.RoleSystem:
+			system = append(system, text)
+		case prompt.RoleUser, prompt.RoleAssistant:
+			req.Messages = append(req.Messages, anthropicMessage{Role: string(msg.Role), Content: text})
+		default:
+			return nil, fmt.Errorf("anthropic: unsupported role %q", msg.Role)
+		}
+	}
+	req.System = strings.Join(system, "\n\n")
+
+	return marshal(req)
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/sklinkert/prompt"
+)
+
+// Gemini encodes requests for the Gemini generateContent API. The model is part
+// of the endpoint URL and therefore not included in the body.
+type Gemini struct {
+	// Renderer formats message content (default prompt.TextRenderer)
+	Renderer prompt.Renderer
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+}
+
+type geminiRequest struct {
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent         `json:"contents"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+}
+
+func (e Gemini) Encode(p *prompt.Prompt) ([]byte, error) {
+	return encodePrompt(e, p)
+}
+
+func (e Gemini) EncodeConversation(c *prompt.Conversation, params Params) ([]byte, error) {
+	req := geminiRequest{
+		Contents: make([]geminiContent, 0, len(c.Messages)),
+	}
+
+	for _, msg := range c.Messages {
+		text, err := renderMessage(msg, e.Renderer)
+		if err != nil {
+			return nil, err
+		}
+
+		switch msg.Role {
+		case prompt.RoleSystem:
+			if req.SystemInstruction == nil {
+				req.SystemInstruction = &geminiContent{}
+			}
+			req.SystemInstruction.Parts = append(req.SystemInstruction.Parts, geminiPart{Text: text})
+		case prompt.RoleUser:
+			req.Contents = append(req.Contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: text}}})
+		case prompt.RoleAssistant:
+			req.Contents = append(req.Contents, geminiContent{Role: "model", Parts: []geminiPart{{Text: text}}})
+		default:
+			return nil, fmt.Errorf("gemini: unsupported role %q", msg.Role)
+		}
+	}
+
+	if params.Temperature != nil || params.TopP != nil || params.MaxTokens != 0 {
+		req.GenerationConfig = &geminiGenerationConfig{
+			Temperature:     params.Temperature,
+			TopP:            params.TopP,
+			MaxOutputTokens: params.MaxTokens,
+		}
+	}
+
+	return marshal(req)
+}
@@ -0,0 +1,54 @@
+package provider
+
+import (
+	"errors"
+
+	"github.com/sklinkert/prompt"
+)
+
+// OpenAI encodes requests for the OpenAI Chat Completions API
+type OpenAI struct {
+	// Renderer formats message content (default prompt.TextRenderer)
+	Renderer prompt.Renderer
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model       string          `json:"model"`
+	Messages    []openAIMessage `json:"messages"`
+	Temperature *float64        `json:"temperature,omitempty"`
+	MaxTokens   int             `json:"max_tokens,omitempty"`
+	TopP        *float64        `json:"top_p,omitempty"`
+}
+
+func (e OpenAI) Encode(p *prompt.Prompt) ([]byte, error) {
+	return encodePrompt(e, p)
+}
+
+func (e OpenAI) EncodeConversation(c *prompt.Conversation, params Params) ([]byte, error) {
+	if params.Model == "" {
+		return nil, errors.New("openai: model is required")
+	}
+
+	req := openAIRequest{
+		Model:       params.Model,
+		Messages:    make([]openAIMessage, 0, len(c.Messages)),
+		Temperature: params.Temperature,
+		MaxTokens:   params.MaxTokens,
+		TopP:        params.TopP,
+	}
+
+	for _, msg := range c.Messages {
+		text, err := renderMessage(msg, e.Renderer)
+		if err != nil {
+			return nil, err
+		}
+		req.Messages = append(req.Messages, openAIMessage{Role: string(msg.Role), Content: text})
+	}
+
+	return marshal(req)
+}
@@ -0,0 +1,104 @@
+// Package provider encodes prompts into the JSON request bodies of hosted LLM
+// APIs. Generation parameters are read from the well-known prompt metadata keys
+// ("model", "temperature", "max_tokens", "top_p" and "system_context").
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sklinkert/prompt"
+)
+
+// Encoder turns a prompt into a provider specific request body
+type Encoder interface {
+	Encode(p *prompt.Prompt) ([]byte, error)
+	EncodeConversation(c *prompt.Conversation, params Params) ([]byte, error)
+}
+
+// Params are the generation parameters shared by all providers. Nil pointers
+// and zero values are left out of the request so the provider default applies.
+type Params struct {
+	Model       string
+	Temperature *float64
+	MaxTokens   int
+	TopP        *float64
+}
+
+// ParamsFromPrompt reads the generation parameters from the prompt metadata.
+// Numeric values may be stored as any integer or float type.
+func ParamsFromPrompt(p *prompt.Prompt) (Params, error) {
+	var params Params
+
+	model, err := prompt.MetaModel.Get(p)
+	if err != nil && !errors.Is(err, prompt.ErrMetadataNotFound) {
+		return Params{}, err
+	}
+	params.Model = model
+
+	if params.Temperature, err = optional(p, prompt.MetaTemperature); err != nil {
+		return Params{}, err
+	}
+
+	maxTokens, err := prompt.MetaMaxTokens.Get(p)
+	if err != nil && !errors.Is(err, prompt.ErrMetadataNotFound) {
+		return Params{}, err
+	}
+	if maxTokens < 0 {
+		return Params{}, fmt.Errorf("metadata %q must not be negative, got %d", prompt.MaxTokensKey, maxTokens)
+	}
+	params.MaxTokens = maxTokens
+
+	if params.TopP, err = optional(p, prompt.MetaTopP); err != nil {
+		return Params{}, err
+	}
+
+	return params, nil
+}
+
+// optional returns a pointer to the value of key, or nil if it is not set
+func optional(p *prompt.Prompt, key prompt.Key[float64]) (*float64, error) {
+	value, err := key.Get(p)
+	if errors.Is(err, prompt.ErrMetadataNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// renderMessage renders one conversation message, falling back to the
+// classic text layout when no renderer is configured
+func renderMessage(msg prompt.Message, r prompt.Renderer) (string, error) {
+	if r == nil {
+		r = prompt.TextRenderer{}
+	}
+	text, err := msg.Render(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s message: %w", msg.Role, err)
+	}
+	return text, nil
+}
+
+// marshal encodes v without escaping HTML characters, which would only make
+// rendered prompts harder to read in logs
+func marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func encodePrompt(e Encoder, p *prompt.Prompt) ([]byte, error) {
+	params, err := ParamsFromPrompt(p)
+	if err != nil {
+		return nil, err
+	}
+	return e.EncodeConversation(p.ToMessages(), params)
+}
@@ -0,0 +1,338 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Detector finds one kind of personal data. Matches of Pattern for which
+// Valid returns true (or all matches if Valid is nil) are replaced by
+// placeholders named after Name, e.g. [EMAIL_1].
+type Detector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Valid   func(match string) bool
+	// Shrink tries shorter spans of whole words when a match fails Valid, for
+	// patterns that can pick up neighbouring words or digit groups
+	Shrink bool
+}
+
+// Built-in detectors. IBANs and credit card numbers are verified with their
+// checksums; phone numbers need a leading '+' or '(', or at least ten digits
+// written with separators, so dates, timestamps and IDs are left alone.
+var (
+	EmailDetector = Detector{
+		Name:    "EMAIL",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	}
+	IBANDetector = Detector{
+		Name:    "IBAN",
+		Pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`),
+		Valid:   validIBAN,
+		Shrink:  true,
+	}
+	CreditCardDetector = Detector{
+		Name:    "CREDIT_CARD",
+		Pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Valid:   validLuhn,
+		Shrink:  true,
+	}
+	PhoneDetector = Detector{
+		Name:    "PHONE",
+		Pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{1,4}\)[ .-]?)?\d{2,4}(?:[ .-]?\d{2,4}){1,4}`),
+		Valid:   validPhone,
+	}
+)
+
+// DefaultDetectors returns the built-in detectors in the order they are
+// applied: emails, IBANs, credit card numbers, phone numbers
+func DefaultDetectors() []Detector {
+	return []Detector{EmailDetector, IBANDetector, CreditCardDetector, PhoneDetector}
+}
+
+// Redactor replaces personal data in prompts with placeholders
+type Redactor struct {
+	detectors []Detector
+}
+
+// NewRedactor returns a redactor applying the detectors in order. Without
+// detectors the DefaultDetectors are used. Custom detectors can be added to
+// the defaults:
+//
+//	prompt.NewRedactor(append(prompt.DefaultDetectors(), prompt.Detector{
+//		Name:    "EMPLOYEE_ID",
+//		Pattern: regexp.MustCompile(`EMP-\d{6}`),
+//	})...)
+func NewRedactor(detectors ...Detector) *Redactor {
+	if len(detectors) == 0 {
+		detectors = DefaultDetectors()
+	}
+	return &Redactor{detectors: detectors}
+}
+
+// Redactions maps placeholders to the values they replaced. The same value
+// always gets the same placeholder, so a Redactions can be shared by all
+// prompts and messages of a conversation. It is not safe for concurrent use.
+type Redactions struct {
+	originals    map[string]string // placeholder -> value
+	placeholders map[string]string // name + "\x00" + value -> placeholder
+	counts       map[string]int    // name -> placeholders issued
+}
+
+// NewRedactions returns an empty mapping for RedactWith
+func NewRedactions() *Redactions {
+	return &Redactions{
+		originals:    make(map[string]string),
+		placeholders: make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+// Redact returns a copy of p with personal data in intros, instructions, data
+// block labels and content, and string metadata values replaced by
+// placeholders, together with the mapping to restore them. p is not modified.
+func (r *Redactor) Redact(p *Prompt) (*Prompt, *Redactions) {
+	m := NewRedactions()
+	return r.RedactWith(p, m), m
+}
+
+// RedactWith is like Redact but adds to an existing mapping, so values seen
+// before keep their placeholders
+func (r *Redactor) RedactWith(p *Prompt, m *Redactions) *Prompt {
+	c := p.Clone()
+
+	for i := range c.Sections {
+		section := &c.Sections[i]
+		section.Intro = r.RedactText(section.Intro, m)
+		for j, instruction := range section.Instructions {
+			section.Instructions[j] = Instruction(r.RedactText(string(instruction), m))
+		}
+		for j := range section.DataBlocks {
+			block := &section.DataBlocks[j]
+			block.Label = r.RedactText(block.Label, m)
+			if block.Type == "json" && json.Valid([]byte(block.Content)) {
+				block.Content = r.redactJSON(block.Content, m)
+			} else {
+				block.Content = r.RedactText(block.Content, m)
+			}
+		}
+	}
+
+	for key, value := range c.metadata {
+		if s, ok := value.(string); ok {
+			c.metadata[key] = r.RedactText(s, m)
+		}
+	}
+
+	return c
+}
+
+// RedactText replaces personal data in text, recording placeholders in m.
+// text is treated as plain text; Redact and RedactWith keep JSON data blocks
+// valid.
+func (r *Redactor) RedactText(text string, m *Redactions) string {
+	for _, d := range r.detectors {
+		text = d.redact(text, m)
+	}
+	return text
+}
+
+// jsonToken matches a JSON string literal or number
+var jsonToken = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|-?\d+(?:\.\d+)?(?:[eE][+-]?\d+)?`)
+
+// redactJSON redacts the strings and numbers of valid JSON content one by
+// one. A number holding personal data becomes a quoted placeholder, so the
+// content stays valid JSON.
+func (r *Redactor) redactJSON(content string, m *Redactions) string {
+	return jsonToken.ReplaceAllStringFunc(content, func(token string) string {
+		if token[0] == '"' {
+			return `"` + r.RedactText(token[1:len(token)-1], m) + `"`
+		}
+		if redacted := r.RedactText(token, m); redacted != token {
+			return strconv.Quote(redacted)
+		}
+		return token
+	})
+}
+
+// redact replaces the matches of d in text
+func (d Detector) redact(text string, m *Redactions) string {
+	return d.Pattern.ReplaceAllStringFunc(text, func(match string) string {
+		return d.redactMatch(match, m)
+	})
+}
+
+// redactMatch replaces match, or the valid values inside it, by placeholders.
+// Patterns like IBANDetector's also pick up neighbouring words and digit
+// groups ("DE89 3704 0044 0532 0130 00 EUR"), which then fail the checksum,
+// so with Shrink set, spans of whole words inside the match are tried, longest
+// first.
+func (d Detector) redactMatch(match string, m *Redactions) string {
+	if d.Valid == nil || d.Valid(match) {
+		return m.placeholder(d.Name, match)
+	}
+	if !d.Shrink {
+		return match
+	}
+
+	var starts, ends []int
+	for i := 0; i < len(match); i++ {
+		if !isWordByte(match[i]) {
+			continue
+		}
+		if i == 0 || !isWordByte(match[i-1]) {
+			starts = append(starts, i)
+		}
+		if i == len(match)-1 || !isWordByte(match[i+1]) {
+			ends = append(ends, i+1)
+		}
+	}
+
+	type span struct{ start, end int }
+	var spans []span
+	for _, start := range starts {
+		for _, end := range ends {
+			if end > start && end-start < len(match) {
+				spans = append(spans, span{start, end})
+			}
+		}
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return spans[i].end-spans[i].start > spans[j].end-spans[j].start
+	})
+
+	for _, sp := range spans {
+		value := match[sp.start:sp.end]
+		loc := d.Pattern.FindStringIndex(value)
+		if loc == nil || loc[0] != 0 || loc[1] != len(value) || !d.Valid(value) {
+			continue
+		}
+		return d.redact(match[:sp.start], m) + m.placeholder(d.Name, value) + d.redact(match[sp.end:], m)
+	}
+	return match
+}
+
+func isWordByte(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+func (m *Redactions) placeholder(name, value string) string {
+	key := name + "\x00" + value
+	if placeholder, ok := m.placeholders[key]; ok {
+		return placeholder
+	}
+	m.counts[name]++
+	placeholder := fmt.Sprintf("[%s_%d]", name, m.counts[name])
+	m.placeholders[key] = placeholder
+	m.originals[placeholder] = value
+	return placeholder
+}
+
+// Restore replaces all placeholders in text, e.g. a model response, with the
+// original values
+func (m *Redactions) Restore(text string) string {
+	if len(m.originals) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(m.originals))
+	for placeholder, value := range m.originals {
+		pairs = append(pairs, placeholder, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Map returns a copy of the placeholder to value mapping
+func (m *Redactions) Map() map[string]string {
+	c := make(map[string]string, len(m.originals))
+	for placeholder, value := range m.originals {
+		c[placeholder] = value
+	}
+	return c
+}
+
+// Placeholders returns all placeholders issued so far, sorted
+func (m *Redactions) Placeholders() []string {
+	placeholders := make([]string, 0, len(m.originals))
+	for placeholder := range m.originals {
+		placeholders = append(placeholders, placeholder)
+	}
+	sort.Strings(placeholders)
+	return placeholders
+}
+
+// validIBAN checks the length and the ISO 13616 mod 97 checksum
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	// move the country code and check digits to the end, letters become 10..35
+	var digits strings.Builder
+	for _, c := range iban[4:] + iban[:4] {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c >= 'A' && c <= 'Z':
+			fmt.Fprintf(&digits, "%d", c-'A'+10)
+		default:
+			return false
+		}
+	}
+
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validLuhn checks the Luhn checksum used by credit card numbers
+func validLuhn(match string) bool {
+	var sum, count int
+	double := false
+	for i := len(match) - 1; i >= 0; i-- {
+		c := match[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		count++
+		double = !double
+	}
+	return count >= 13 && count <= 19 && sum%10 == 0
+}
+
+var isoDate = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+// validPhone accepts 7 to 15 digits (the E.164 maximum) written with a
+// country code or area code in parentheses, or at least ten digits split by
+// separators otherwise. Bare digit runs like Unix timestamps and order IDs
+// and dates like 2024-01-15 are rejected.
+func validPhone(match string) bool {
+	if isoDate.MatchString(match) {
+		return false
+	}
+	var digits int
+	for _, c := range match {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	if digits < 7 || digits > 15 {
+		return false
+	}
+	if strings.HasPrefix(match, "+") || strings.HasPrefix(match, "(") {
+		return true
+	}
+	return digits >= 10 && strings.ContainsAny(match, " .-")
+}
@@ -0,0 +1,153 @@
+package prompt
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestRedactText(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"email", "Contact jane.doe@example.com today", "Contact [EMAIL_1] today"},
+		{"iban", "Pay to DE89 3704 0044 0532 0130 00 now", "Pay to [IBAN_1] now"},
+		{"compact iban", "IBAN GB82WEST12345698765432", "IBAN [IBAN_1]"},
+		{"invalid iban", "Code DE00 3704 0044 0532 0130 00", "Code DE00 3704 0044 0532 0130 00"},
+		{"credit card", "Card 4111 1111 1111 1111 expires", "Card [CREDIT_CARD_1] expires"},
+		{"invalid credit card", "Order 4111111111111112", "Order 4111111111111112"},
+		{"international phone", "Call +49 30 1234567", "Call [PHONE_1]"},
+		{"us phone", "Call (555) 123-4567 or 555-987-6543", "Call [PHONE_1] or [PHONE_2]"},
+		{"date", "Due 2024-01-15 10:30", "Due 2024-01-15 10:30"},
+		{"short number", "Order 12345", "Order 12345"},
+		{"timestamp and id", `{"created_at": 1700000000, "order_id": 12345678901}`, `{"created_at": 1700000000, "order_id": 12345678901}`},
+		{"bare phone with country code", "Call +4930123456789", "Call [PHONE_1]"},
+		{"iban before currency", "DE89 3704 0044 0532 0130 00 EUR", "[IBAN_1] EUR"},
+		{"iban before bic", "IBAN DE89 3704 0044 0532 0130 00 BIC COBADEFFXXX", "IBAN [IBAN_1] BIC COBADEFFXXX"},
+		{"iban after digits", "ref 12 DE89370400440532013000", "ref 12 [IBAN_1]"},
+		{"card before digit group", "card 4111 1111 1111 1111 12 units", "card [CREDIT_CARD_1] 12 units"},
+		{"card after digit group", "order 12 4111-1111-1111-1111", "order 12 [CREDIT_CARD_1]"},
+		{"repeated value", "a@b.io and a@b.io and c@d.io", "[EMAIL_1] and [EMAIL_1] and [EMAIL_2]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewRedactor().RedactText(tt.input, NewRedactions())
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRedactPrompt(t *testing.T) {
+	p := NewPrompt()
+	section := NewSection("Reply to jane@example.com")
+	section.AddInstruction("Mention the IBAN DE89370400440532013000")
+	section.AddInstruction("Do not call +1 415 555 0100")
+	section.AddRawJSON("Customer", `{"email": "jane@example.com", "card": "4111-1111-1111-1111"}`)
+	p.AddSection(section)
+	p.SetMetadata(SystemContextKey, "Support agent for jane@example.com")
+	p.SetMetadata(TemperatureKey, 0.7)
+
+	original := p.String()
+	redacted, m := NewRedactor().Redact(p)
+
+	if p.String() != original {
+		t.Error("Expected Redact not to modify the prompt")
+	}
+
+	expected := "\nReply to [EMAIL_1]:\n- Mention the IBAN [IBAN_1]\n- Do not call [PHONE_1]\n\nCustomer:\n```json\n" +
+		`{"email": "[EMAIL_1]", "card": "[CREDIT_CARD_1]"}` + "\n```\n---"
+	if redacted.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, redacted.String())
+	}
+
+	if got, _ := redacted.GetMetadata(SystemContextKey); got != "Support agent for [EMAIL_1]" {
+		t.Errorf("Expected redacted system context, got %q", got)
+	}
+	if got, _ := redacted.GetMetadata(TemperatureKey); got != 0.7 {
+		t.Errorf("Expected non-string metadata to be kept, got %v", got)
+	}
+
+	expectedMap := map[string]string{
+		"[EMAIL_1]":       "jane@example.com",
+		"[IBAN_1]":        "DE89370400440532013000",
+		"[CREDIT_CARD_1]": "4111-1111-1111-1111",
+		"[PHONE_1]":       "+1 415 555 0100",
+	}
+	if !reflect.DeepEqual(m.Map(), expectedMap) {
+		t.Errorf("Expected mapping %v, got %v", expectedMap, m.Map())
+	}
+	if !reflect.DeepEqual(m.Placeholders(), []string{"[CREDIT_CARD_1]", "[EMAIL_1]", "[IBAN_1]", "[PHONE_1]"}) {
+		t.Errorf("Unexpected placeholders %v", m.Placeholders())
+	}
+
+	response := "Dear [EMAIL_1], we refunded [CREDIT_CARD_1]."
+	if got := m.Restore(response); got != "Dear jane@example.com, we refunded 4111-1111-1111-1111." {
+		t.Errorf("Unexpected restored response %q", got)
+	}
+}
+
+func TestRedactJSONBlock(t *testing.T) {
+	p := NewPrompt()
+	section := NewSection("Orders")
+	section.AddRawJSON("Order", `{"created_at": 1700000000, "order_id": 12345678901, "card": 4111111111111111, "email": "jane@example.com", "total": -12.5e2}`)
+	p.AddSection(section)
+
+	redacted, m := NewRedactor().Redact(p)
+	content := redacted.Sections[0].DataBlocks[0].Content
+	if !json.Valid([]byte(content)) {
+		t.Fatalf("Expected valid JSON, got %s", content)
+	}
+
+	expected := `{"created_at": 1700000000, "order_id": 12345678901, "card": "[CREDIT_CARD_1]", "email": "[EMAIL_1]", "total": -12.5e2}`
+	if content != expected {
+		t.Errorf("Expected %s, got %s", expected, content)
+	}
+	if len(m.Map()) != 2 {
+		t.Errorf("Expected only the card and email in the mapping, got %v", m.Map())
+	}
+}
+
+func TestRedactWithSharedMapping(t *testing.T) {
+	r := NewRedactor()
+	m := NewRedactions()
+
+	first := NewPrompt()
+	first.AddSection(Section{Intro: "Email a@b.io"})
+	second := NewPrompt()
+	second.AddSection(Section{Intro: "Email c@d.io and a@b.io"})
+
+	r.RedactWith(first, m)
+	redacted := r.RedactWith(second, m)
+
+	if redacted.Sections[0].Intro != "Email [EMAIL_2] and [EMAIL_1]" {
+		t.Errorf("Expected placeholders to be stable across prompts, got %q", redacted.Sections[0].Intro)
+	}
+}
+
+func TestRedactCustomDetector(t *testing.T) {
+	r := NewRedactor(append(DefaultDetectors(), Detector{
+		Name:    "EMPLOYEE_ID",
+		Pattern: regexp.MustCompile(`EMP-\d{6}`),
+	})...)
+	m := NewRedactions()
+
+	got := r.RedactText("EMP-123456 (emp@corp.com) and EMP-654321", m)
+	if got != "[EMPLOYEE_ID_1] ([EMAIL_1]) and [EMPLOYEE_ID_2]" {
+		t.Errorf("Unexpected redaction %q", got)
+	}
+	if m.Restore(got) != "EMP-123456 (emp@corp.com) and EMP-654321" {
+		t.Errorf("Unexpected restore %q", m.Restore(got))
+	}
+}
+
+func TestRestoreWithoutRedactions(t *testing.T) {
+	if got := NewRedactions().Restore("[EMAIL_1]"); got != "[EMAIL_1]" {
+		t.Errorf("Expected text to be unchanged, got %q", got)
+	}
+}
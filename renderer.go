@@ -0,0 +1,222 @@
+package prompt
+
+import (
+	"io"
+	"strings"
+)
+
+// Renderer formats a prompt or a single section into the text sent to a model.
+// Implementations must not modify the sections they are given.
+type Renderer interface {
+	RenderPrompt(w io.Writer, p *Prompt) error
+	RenderSection(w io.Writer, s Section) error
+}
+
+// TextRenderer renders the classic layout: intro with a colon, "- " bullets,
+// fenced data blocks and "---" separators between sections.
+type TextRenderer struct{}
+
+func (r TextRenderer) RenderPrompt(w io.Writer, p *Prompt) error {
+	_, err := Sections(p.Sections).WriteTo(w)
+	return err
+}
+
+func (r TextRenderer) RenderSection(w io.Writer, s Section) error {
+	_, err := s.WriteTo(w)
+	return err
+}
+
+// MarkdownRenderer renders each section under a Markdown heading with bullet
+// point instructions and fenced data blocks. Sections are separated by a single
+// blank line; sections that render nothing are skipped.
+type MarkdownRenderer struct {
+	// HeadingLevel is the number of '#' used for section headings (default 2)
+	HeadingLevel int
+}
+
+func (r MarkdownRenderer) RenderPrompt(w io.Writer, p *Prompt) error {
+	rw := &renderWriter{w: w}
+	var wrote bool
+	for _, section := range renderOrder(p.Sections) {
+		// sections without heading, instructions or data render nothing
+		if strings.TrimSuffix(section.Intro, ":") == "" && len(section.Instructions) == 0 && len(section.DataBlocks) == 0 {
+			continue
+		}
+		// sections end with a newline, one more leaves a single blank line
+		if wrote {
+			rw.str("\n")
+		}
+		if err := r.RenderSection(rw, section); err != nil {
+			return err
+		}
+		wrote = true
+	}
+	return rw.err
+}
+
+func (r MarkdownRenderer) RenderSection(w io.Writer, s Section) error {
+	level := r.HeadingLevel
+	if level <= 0 {
+		level = 2
+	}
+
+	rw := &renderWriter{w: w}
+	var needBlank bool
+
+	if intro := strings.TrimSuffix(s.Intro, ":"); intro != "" {
+		rw.str(strings.Repeat("#", level) + " " + intro + "\n")
+		needBlank = true
+	}
+
+	if len(s.Instructions) > 0 {
+		if needBlank {
+			rw.str("\n")
+		}
+		for _, instruction := range s.Instructions {
+			rw.str("- " + string(instruction) + "\n")
+		}
+		needBlank = true
+	}
+
+	for _, block := range s.DataBlocks {
+		if needBlank {
+			rw.str("\n")
+		}
+		if block.Label != "" {
+			rw.str("**" + block.Label + "**\n\n")
+		}
+		content := block.renderedContent()
+		fence := fenceFor(content)
+		if block.Trust == Untrusted {
+			begin, end := block.delimiters()
+			rw.str(untrustedPreamble + "\n\n" + begin + "\n")
+			rw.str(fence + block.Type + "\n" + content + "\n" + fence + "\n")
+			rw.str(end + "\n")
+		} else {
+			rw.str(fence + block.Type + "\n" + content + "\n" + fence + "\n")
+		}
+		needBlank = true
+	}
+
+	return rw.err
+}
+
+// XMLRenderer renders Anthropic-style tagged prompts: every section becomes
+// <section name="..."> with one <instruction> element per instruction, and every
+// data block becomes <document label="..." type="...">. Text and attribute
+// values are escaped, so content cannot close or inject tags. Untrusted data
+// blocks are preceded by an <instruction> with the untrusted data preamble and
+// get a trust="untrusted" attribute instead of nonce delimiters; their content
+// is sanitized like in the other renderers.
+type XMLRenderer struct{}
+
+func (r XMLRenderer) RenderPrompt(w io.Writer, p *Prompt) error {
+	rw := &renderWriter{w: w}
+	for i, section := range renderOrder(p.Sections) {
+		if i > 0 {
+			rw.str("\n")
+		}
+		if err := r.RenderSection(rw, section); err != nil {
+			return err
+		}
+	}
+	return rw.err
+}
+
+func (r XMLRenderer) RenderSection(w io.Writer, s Section) error {
+	rw := &renderWriter{w: w}
+
+	if intro := strings.TrimSuffix(s.Intro, ":"); intro != "" {
+		rw.str("<section name=" + quoteAttr(intro) + ">\n")
+	} else {
+		rw.str("<section>\n")
+	}
+
+	for _, instruction := range s.Instructions {
+		rw.str("<instruction>" + xmlTextEscaper.Replace(string(instruction)) + "</instruction>\n")
+	}
+
+	for _, block := range s.DataBlocks {
+		if block.Trust == Untrusted {
+			rw.str("<instruction>" + untrustedPreamble + "</instruction>\n")
+		}
+		rw.str("<document")
+		if block.Label != "" {
+			rw.str(" label=" + quoteAttr(block.Label))
+		}
+		if block.Type != "" {
+			rw.str(" type=" + quoteAttr(block.Type))
+		}
+		if block.Trust == Untrusted {
+			rw.str(` trust="untrusted"`)
+		}
+		rw.str(">\n" + xmlTextEscaper.Replace(block.renderedContent()) + "\n</document>\n")
+	}
+
+	rw.str("</section>")
+	return rw.err
+}
+
+// Render formats the prompt with the given renderer
+func (p *Prompt) Render(r Renderer) (string, error) {
+	var b strings.Builder
+	if err := r.RenderPrompt(&b, p); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Render formats the section with the given renderer
+func (s Section) Render(r Renderer) (string, error) {
+	var b strings.Builder
+	if err := r.RenderSection(&b, s); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// renderWriter remembers the first write error so renderers can emit many
+// small pieces without checking every call.
+type renderWriter struct {
+	w   io.Writer
+	n   int64 // bytes written
+	err error
+}
+
+func (rw *renderWriter) Write(b []byte) (int, error) {
+	if rw.err != nil {
+		return 0, rw.err
+	}
+	n, err := rw.w.Write(b)
+	rw.n += int64(n)
+	rw.err = err
+	return n, err
+}
+
+// WriteString lets nested renderWriters pass strings on without copying
+func (rw *renderWriter) WriteString(s string) (int, error) {
+	if rw.err != nil {
+		return 0, rw.err
+	}
+	n, err := io.WriteString(rw.w, s)
+	rw.n += int64(n)
+	rw.err = err
+	return n, err
+}
+
+func (rw *renderWriter) str(s string) {
+	_, _ = rw.WriteString(s)
+}
+
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer(
+		"&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;",
+		"\n", "&#10;", "\r", "&#13;", "\t", "&#9;",
+	)
+)
+
+// quoteAttr returns s escaped and quoted for use as an XML attribute value
+func quoteAttr(s string) string {
+	return `"` + xmlAttrEscaper.Replace(s) + `"`
+}
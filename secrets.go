@@ -0,0 +1,322 @@
+package prompt
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SecretKind names the kind of credential a SecretFinding looks like
+type SecretKind string
+
+const (
+	SecretPrivateKey   SecretKind = "private key"
+	SecretJWT          SecretKind = "JWT"
+	SecretAWSAccessKey SecretKind = "AWS access key"
+	SecretAWSSecretKey SecretKind = "AWS secret key"
+	SecretAPIKey       SecretKind = "API key"
+	SecretHighEntropy  SecretKind = "high-entropy string"
+)
+
+// secretPatterns are checked in order; a later pattern does not report text
+// an earlier one already matched. The first submatch, if any, is the secret.
+var secretPatterns = []struct {
+	kind    SecretKind
+	pattern *regexp.Regexp
+}{
+	{SecretPrivateKey, regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`)},
+	{SecretJWT, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{5,}\.eyJ[A-Za-z0-9_-]{5,}\.[A-Za-z0-9_-]{10,}`)},
+	{SecretAWSAccessKey, regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`)},
+	{SecretAWSSecretKey, regexp.MustCompile(`(?i)(?:aws.{0,20}secret|secret.?access.?key).{0,20}?[=:>"'\s]\s*["']?([A-Za-z0-9/+]{40})(?:[^A-Za-z0-9/+=]|$)`)},
+	{SecretAPIKey, regexp.MustCompile(`\b(?:` +
+		`sk-[A-Za-z0-9_-]{20,}` + // OpenAI, Anthropic
+		`|gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,}` + // GitHub
+		`|xox[abprs]-[A-Za-z0-9-]{10,}` + // Slack
+		`|AIza[A-Za-z0-9_-]{35}` + // Google
+		`|[rs]k_live_[A-Za-z0-9]{24,}` + // Stripe
+		`)`)},
+}
+
+var privateKeyEnd = regexp.MustCompile(`-----END (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`)
+
+// highEntropyToken finds candidates for SecretHighEntropy: long runs of
+// base64 and URL-safe characters
+var highEntropyToken = regexp.MustCompile(`[A-Za-z0-9+/=_-]{24,}`)
+
+// minSecretEntropy is the Shannon entropy in bits per character above which
+// a token counts as random. Random base64 strings of 24+ characters are above
+// it, English text, hex hashes and UUIDs below.
+const minSecretEntropy = 4.0
+
+// SecretFinding is one suspected credential found by ScanSecrets. Field says
+// which part of the prompt holds it: "intro", "instruction", "label",
+// "content" (of a data block), "metadata" or "text" (plain Text message
+// content checked by SecretGuard).
+type SecretFinding struct {
+	Kind        SecretKind
+	Field       string
+	Section     int    // index into Prompt.Sections, -1 for metadata and text
+	Intro       string // intro of the section, with secrets masked
+	Block       int    // index into DataBlocks, -1 unless Field is label or content
+	Label       string // label of the data block, with secrets masked
+	Instruction int    // index into Instructions, -1 unless Field is instruction
+	Key         string // metadata key
+	Line        int    // 1-based line within the scanned text
+	Preview     string // start of the secret with the rest masked
+}
+
+func (f SecretFinding) String() string {
+	var location string
+	switch f.Field {
+	case "metadata":
+		location = fmt.Sprintf("metadata %q", f.Key)
+	case "text":
+		location = "text"
+	default:
+		location = fmt.Sprintf("section %d %q", f.Section, f.Intro)
+		switch f.Field {
+		case "intro":
+			location += " intro"
+		case "instruction":
+			location += fmt.Sprintf(", instruction %d", f.Instruction)
+		case "label":
+			location += fmt.Sprintf(", data block %d label", f.Block)
+		default:
+			location += fmt.Sprintf(", data block %d %q", f.Block, f.Label)
+		}
+	}
+	return fmt.Sprintf("%s line %d: %s (%s)", location, f.Line, f.Kind, f.Preview)
+}
+
+// SecretsError is returned by SecretGuard when a prompt contains secrets
+type SecretsError struct {
+	Findings []SecretFinding
+}
+
+func (e *SecretsError) Error() string {
+	lines := make([]string, len(e.Findings))
+	for i, f := range e.Findings {
+		lines[i] = f.String()
+	}
+	return fmt.Sprintf("prompt contains %d possible secret(s): %s", len(e.Findings), strings.Join(lines, "; "))
+}
+
+// ScanSecrets looks for API keys, JWTs, private key blocks, AWS credentials
+// and high-entropy strings in the intro, instructions, data block labels and
+// data block content of every section and in all string metadata values,
+// including system_context. Findings only hold masked previews of secrets,
+// so the report itself is safe to log.
+func ScanSecrets(p *Prompt) []SecretFinding {
+	var findings []SecretFinding
+	for i, section := range p.Sections {
+		findings = append(findings, scanSection(i, section)...)
+	}
+
+	metadata := p.GetAllMetadata()
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value, ok := metadata[key].(string); ok {
+			for _, f := range scanText("metadata", value) {
+				f.Key = key
+				findings = append(findings, f)
+			}
+		}
+	}
+
+	return findings
+}
+
+func scanSection(index int, s Section) []SecretFinding {
+	var findings []SecretFinding
+	intro := maskSecrets(s.Intro)
+
+	add := func(found []SecretFinding, block, instruction int, label string) {
+		for _, f := range found {
+			f.Section, f.Intro, f.Block, f.Instruction, f.Label = index, intro, block, instruction, label
+			findings = append(findings, f)
+		}
+	}
+
+	add(scanText("intro", s.Intro), -1, -1, "")
+	for j, instruction := range s.Instructions {
+		add(scanText("instruction", string(instruction)), -1, j, "")
+	}
+	for j, block := range s.DataBlocks {
+		label := maskSecrets(block.Label)
+		add(scanText("label", block.Label), j, -1, label)
+		add(scanText("content", block.Content), j, -1, label)
+	}
+
+	return findings
+}
+
+// secretMatch is a secret at bytes start:end of line (0-based)
+type secretMatch struct {
+	kind       SecretKind
+	line       int
+	start, end int
+}
+
+// scanText returns the findings in text with Kind, Field, Line and Preview
+// set and all indexes at -1
+func scanText(field, text string) []SecretFinding {
+	lines := strings.Split(text, "\n")
+	var findings []SecretFinding
+	for _, m := range findSecrets(lines) {
+		findings = append(findings, SecretFinding{
+			Kind:        m.kind,
+			Field:       field,
+			Section:     -1,
+			Block:       -1,
+			Instruction: -1,
+			Line:        m.line + 1,
+			Preview:     maskSecret(lines[m.line][m.start:m.end]),
+		})
+	}
+	return findings
+}
+
+// maskSecrets replaces every secret in text by its masked preview
+func maskSecrets(text string) string {
+	lines := strings.Split(text, "\n")
+	matches := findSecrets(lines)
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		line := lines[m.line]
+		lines[m.line] = line[:m.start] + maskSecret(line[m.start:m.end]) + line[m.end:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findSecrets returns the secrets in lines, ordered by position. The body of
+// a private key block is reported once, with its BEGIN line.
+func findSecrets(lines []string) []secretMatch {
+	var found []secretMatch
+	var inKey bool
+
+	for n, line := range lines {
+		if inKey {
+			inKey = !privateKeyEnd.MatchString(line)
+			continue
+		}
+
+		var matches []secretMatch
+		report := func(kind SecretKind, start, end int) {
+			for _, m := range matches {
+				if start < m.end && m.start < end {
+					return
+				}
+			}
+			matches = append(matches, secretMatch{kind, n, start, end})
+		}
+
+		for _, sp := range secretPatterns {
+			for _, m := range sp.pattern.FindAllStringSubmatchIndex(line, -1) {
+				if len(m) >= 4 && m[2] >= 0 {
+					report(sp.kind, m[2], m[3])
+				} else {
+					report(sp.kind, m[0], m[1])
+				}
+			}
+		}
+
+		for _, m := range highEntropyToken.FindAllStringIndex(line, -1) {
+			if token := line[m[0]:m[1]]; looksRandom(token) {
+				report(SecretHighEntropy, m[0], m[1])
+			}
+		}
+
+		sort.Slice(matches, func(a, b int) bool { return matches[a].start < matches[b].start })
+		for _, m := range matches {
+			if m.kind == SecretPrivateKey && !privateKeyEnd.MatchString(line[m.end:]) {
+				inKey = true
+			}
+		}
+		found = append(found, matches...)
+	}
+
+	return found
+}
+
+// looksRandom reports whether a token mixes letters with at least two digits
+// (ruling out identifiers like "userAccountSettingsName2") and has a high
+// Shannon entropy
+func looksRandom(token string) bool {
+	var letters, digits int
+	counts := make(map[rune]int)
+	for _, c := range token {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+			letters++
+		}
+		counts[c]++
+	}
+	if letters == 0 || digits < 2 {
+		return false
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(len(token))
+		entropy -= p * math.Log2(p)
+	}
+	return entropy >= minSecretEntropy
+}
+
+// maskSecret keeps the first four characters of a secret, enough to tell
+// "AKIA" from "sk-" in a report
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return secret[:4] + strings.Repeat("*", min(len(secret)-4, 8))
+}
+
+// SecretGuard wraps a renderer and refuses to render prompts, sections and
+// plain Text that contain secrets, returning a *SecretsError instead. Prompts
+// are checked with ScanSecrets, so string metadata such as system_context is
+// covered as well. Use it wherever a Renderer is accepted, e.g. as the
+// Renderer of a provider encoder, which then also checks Text messages. A nil
+// Renderer means TextRenderer.
+type SecretGuard struct {
+	Renderer Renderer
+}
+
+func (g SecretGuard) RenderPrompt(w io.Writer, p *Prompt) error {
+	if findings := ScanSecrets(p); len(findings) > 0 {
+		return &SecretsError{Findings: findings}
+	}
+	return g.renderer().RenderPrompt(w, p)
+}
+
+// RenderSection checks a single section; findings have Section set to 0
+func (g SecretGuard) RenderSection(w io.Writer, s Section) error {
+	if findings := scanSection(0, s); len(findings) > 0 {
+		return &SecretsError{Findings: findings}
+	}
+	return g.renderer().RenderSection(w, s)
+}
+
+// CheckText checks plain Text message content
+func (g SecretGuard) CheckText(text string) error {
+	if findings := scanText("text", text); len(findings) > 0 {
+		return &SecretsError{Findings: findings}
+	}
+	return nil
+}
+
+func (g SecretGuard) renderer() Renderer {
+	if g.Renderer == nil {
+		return TextRenderer{}
+	}
+	return g.Renderer
+}
@@ -4,18 +4,29 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"strings"
 )
 
 type DataBlock struct {
-	Label   string
-	Content string
-	Type    string // "json", "xml", or "html"
+	Label   string `json:"label,omitempty"`
+	Content string `json:"content"`
+	Type    string `json:"type"` // "json", "xml", "html", or a table format ("markdown", "csv", "tsv")
+	Trust   Trust  `json:"trust,omitempty"`
 }
 
 type Section struct {
-	Intro        string
-	Instructions []Instruction
-	DataBlocks   []DataBlock
+	Intro        string        `json:"intro,omitempty"`
+	Instructions []Instruction `json:"instructions,omitempty"`
+	DataBlocks   []DataBlock   `json:"data_blocks,omitempty"`
+
+	// Priority ranks the section for trimming; lower priorities are dropped first
+	Priority int `json:"priority,omitempty"`
+	// Required sections are never dropped and keep all their instructions and
+	// data blocks
+	Required bool `json:"required,omitempty"`
+	// PinToEnd renders the section after all unpinned sections
+	PinToEnd bool `json:"pin_to_end,omitempty"`
 }
 
 type Sections []Section
@@ -87,65 +98,163 @@ func (s *Section) AddRawHTML(label string, htmlString string) {
 	})
 }
 
-func (s *Section) String() string {
-	var output string
+// String renders the section. It does not modify the section and is safe to
+// call from multiple goroutines.
+func (s Section) String() string {
+	var b strings.Builder
+	_, _ = s.WriteTo(&b)
+	return b.String()
+}
+
+// WriteTo streams the section as String renders it to w
+func (s Section) WriteTo(w io.Writer) (int64, error) {
+	rw := &renderWriter{w: w}
 
-	if s.Intro != "" {
-		// if last char of intro is not ':', add ':'
-		if s.Intro[len(s.Intro)-1] != ':' {
-			s.Intro += ":"
+	// lines are separated by newlines; the last line has no trailing newline
+	first := true
+	newline := func() {
+		if !first {
+			rw.str("\n")
 		}
+		first = false
+	}
 
-		output += s.Intro + "\n"
+	if s.Intro != "" {
+		newline()
+		rw.str(introLine(s.Intro))
 	}
 
 	for _, instruction := range s.Instructions {
-		output += "- " + string(instruction) + "\n"
+		newline()
+		rw.str("- ")
+		rw.str(string(instruction))
 	}
 
 	// Add data blocks after instructions
 	for _, block := range s.DataBlocks {
 		// Add blank line before data block if there are instructions
 		if len(s.Instructions) > 0 {
-			output += "\n"
+			newline()
 		}
 
 		// Add label if provided
 		if block.Label != "" {
-			output += block.Label + ":\n"
+			newline()
+			rw.str(block.Label)
+			rw.str(":")
+		}
+
+		var begin, end string
+		if block.Trust == Untrusted {
+			begin, end = block.delimiters()
+			newline()
+			rw.str(untrustedPreamble)
+			newline()
+			rw.str(begin)
 		}
 
 		// Add code fence with content
-		output += "```" + block.Type + "\n"
-		output += block.Content + "\n"
-		output += "```\n"
-	}
+		content := block.renderedContent()
+		fence := fenceFor(content)
+		newline()
+		rw.str(fence)
+		rw.str(block.Type)
+		newline()
+		rw.str(content)
+		newline()
+		rw.str(fence)
 
-	if output != "" && output[len(output)-1] == '\n' {
-		output = output[:len(output)-1]
+		if block.Trust == Untrusted {
+			newline()
+			rw.str(end)
+		}
 	}
 
-	return output
+	return rw.n, rw.err
 }
 
-func (s *Section) WordsCount() int {
-	var count int
+// fenceFor returns a code fence that cannot be closed by content: three
+// backticks, or one more than the longest run of backticks in content
+func fenceFor(content string) string {
+	longest, run := 0, 0
+	for i := 0; i < len(content); i++ {
+		if content[i] == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 {
+		return "```"
+	}
+	return strings.Repeat("`", longest+1)
+}
 
-	for _, instruction := range s.Instructions {
-		count += instruction.WordCount()
+// introLine returns the intro as String emits it, always ending with ':'
+func introLine(intro string) string {
+	if intro != "" && intro[len(intro)-1] != ':' {
+		return intro + ":"
 	}
+	return intro
+}
 
-	return count
+// WordsCount returns the number of words String emits: intro, instructions,
+// data blocks and their markup (see WordBreakdown)
+func (s Section) WordsCount() int {
+	return s.WordBreakdown().Total()
 }
 
 func (ss Sections) String() string {
-	var output string
+	var b strings.Builder
+	_, _ = ss.WriteTo(&b)
+	return b.String()
+}
+
+// WriteTo streams the sections as String renders them to w, each preceded by
+// a newline and followed by a "---" separator
+func (ss Sections) WriteTo(w io.Writer) (int64, error) {
+	rw := &renderWriter{w: w}
+	for _, section := range renderOrder(ss) {
+		rw.str("\n")
+		_, _ = section.WriteTo(rw)
+		rw.str("\n---")
+	}
+	return rw.n, rw.err
+}
 
-	for _, section := range ss {
-		output += "\n" + section.String() + "\n---"
+// renderOrder returns the sections in the order they are rendered: unpinned
+// sections first, then sections with PinToEnd, each group in its original order
+func renderOrder(sections []Section) []Section {
+	pinned := false
+	for _, section := range sections {
+		pinned = pinned || section.PinToEnd
+	}
+	if !pinned {
+		return sections
 	}
 
-	return output
+	ordered := make([]Section, 0, len(sections))
+	for _, i := range renderIndexes(sections) {
+		ordered = append(ordered, sections[i])
+	}
+	return ordered
+}
+
+// renderIndexes returns the indexes of sections in render order
+func renderIndexes(sections []Section) []int {
+	indexes := make([]int, 0, len(sections))
+	for i, section := range sections {
+		if !section.PinToEnd {
+			indexes = append(indexes, i)
+		}
+	}
+	for i, section := range sections {
+		if section.PinToEnd {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
 }
 
 func WordsCount(sections []Section) int {
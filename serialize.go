@@ -0,0 +1,195 @@
+package prompt
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// SchemaVersion is the version of the JSON document written by
+// Prompt.MarshalJSON. UnmarshalJSON rejects documents from newer versions and
+// reads documents without a version as version 0: prompts encoded before
+// MarshalJSON existed, with Go field names and without metadata.
+const SchemaVersion = 1
+
+// promptJSON is the serialized form of a Prompt. Sections and data blocks use
+// their struct tags. Numeric metadata values are written as plain JSON numbers
+// and their Go type is recorded in MetadataTypes, so an int stays an int and a
+// float64 of 1.0 stays a float64 after a round trip.
+type promptJSON struct {
+	Version       int               `json:"version"`
+	Sections      []Section         `json:"sections"`
+	Metadata      map[string]any    `json:"metadata,omitempty"`
+	MetadataTypes map[string]string `json:"metadata_types,omitempty"`
+}
+
+// MarshalJSON encodes the sections and all metadata. The tokenizer is not
+// part of the document. Metadata values must be JSON encodable. The value
+// receiver makes prompts stored by value, e.g. in struct fields or maps, encode
+// the same way as *Prompt.
+func (p Prompt) MarshalJSON() ([]byte, error) {
+	doc := promptJSON{
+		Version:  SchemaVersion,
+		Sections: p.Sections,
+		Metadata: p.GetAllMetadata(),
+	}
+	if doc.Sections == nil {
+		doc.Sections = []Section{}
+	}
+
+	for key, value := range doc.Metadata {
+		if kind := numberKind(value); kind != "" {
+			if doc.MetadataTypes == nil {
+				doc.MetadataTypes = make(map[string]string)
+			}
+			doc.MetadataTypes[key] = kind
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal prompt: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalJSON restores a prompt written by MarshalJSON or, as version 0, by
+// json.Marshal before it existed. Numbers are restored to their recorded Go
+// type. Other metadata values decode as string, bool, nil, map[string]any or
+// []any.
+func (p *Prompt) UnmarshalJSON(data []byte) error {
+	var doc struct {
+		Version       int                        `json:"version"`
+		Sections      []Section                  `json:"sections"`
+		Metadata      map[string]json.RawMessage `json:"metadata"`
+		MetadataTypes map[string]string          `json:"metadata_types"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to unmarshal prompt: %w", err)
+	}
+
+	if doc.Version > SchemaVersion {
+		return fmt.Errorf("failed to unmarshal prompt: schema version %d is newer than supported version %d", doc.Version, SchemaVersion)
+	}
+
+	metadata := make(map[string]any, len(doc.Metadata))
+	for key, raw := range doc.Metadata {
+		value, err := decodeMetadataValue(raw, doc.MetadataTypes[key])
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal metadata %q: %w", key, err)
+		}
+		metadata[key] = value
+	}
+
+	p.Sections = doc.Sections
+	p.metadata = metadata
+	return nil
+}
+
+// UnmarshalJSON decodes a section written with its struct tags or, before
+// they existed, with the Go field names. encoding/json matches "Intro" and
+// "Instructions" case-insensitively, but not "DataBlocks" to "data_blocks".
+// Sections, data blocks and instructions on their own are encoded through
+// their struct tags and carry no schema version; only Prompt documents do.
+func (s *Section) UnmarshalJSON(data []byte) error {
+	type section Section
+	var doc struct {
+		section
+		LegacyDataBlocks []DataBlock `json:"DataBlocks"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	*s = Section(doc.section)
+	if s.DataBlocks == nil {
+		s.DataBlocks = doc.LegacyDataBlocks
+	}
+	return nil
+}
+
+// numberKind returns the name of value's numeric type, or "" if it is not a number
+func numberKind(value any) string {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return reflect.TypeOf(value).String()
+	}
+	return ""
+}
+
+func decodeMetadataValue(raw json.RawMessage, kind string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	n, ok := value.(json.Number)
+	if !ok {
+		return normalizeNumbers(value), nil
+	}
+
+	s := n.String()
+	switch kind {
+	case "int":
+		i, err := strconv.ParseInt(s, 10, 0)
+		return int(i), err
+	case "int8":
+		i, err := strconv.ParseInt(s, 10, 8)
+		return int8(i), err
+	case "int16":
+		i, err := strconv.ParseInt(s, 10, 16)
+		return int16(i), err
+	case "int32":
+		i, err := strconv.ParseInt(s, 10, 32)
+		return int32(i), err
+	case "int64":
+		return strconv.ParseInt(s, 10, 64)
+	case "uint":
+		u, err := strconv.ParseUint(s, 10, 0)
+		return uint(u), err
+	case "uint8":
+		u, err := strconv.ParseUint(s, 10, 8)
+		return uint8(u), err
+	case "uint16":
+		u, err := strconv.ParseUint(s, 10, 16)
+		return uint16(u), err
+	case "uint32":
+		u, err := strconv.ParseUint(s, 10, 32)
+		return uint32(u), err
+	case "uint64":
+		return strconv.ParseUint(s, 10, 64)
+	case "float32":
+		f, err := strconv.ParseFloat(s, 32)
+		return float32(f), err
+	case "float64":
+		return strconv.ParseFloat(s, 64)
+	}
+	return normalizeNumbers(n), nil
+}
+
+// normalizeNumbers turns json.Number values without a recorded type into int
+// when integral and float64 otherwise
+func normalizeNumbers(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := strconv.Atoi(v.String()); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeNumbers(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeNumbers(item)
+		}
+	}
+	return value
+}
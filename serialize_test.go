@@ -0,0 +1,186 @@
+package prompt
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPromptJSONRoundTrip(t *testing.T) {
+	p := NewPrompt()
+
+	task := NewSection("Summarize")
+	task.AddInstruction("Keep it short")
+	task.AddRawJSON("Input", `{"id": 1}`)
+	task.Required = true
+	p.AddSection(task)
+	p.AddSection(Section{Intro: "Format", Instructions: []Instruction{"Use Markdown"}, Priority: 3, PinToEnd: true})
+
+	p.SetMetadata("model_high_quality", true)
+	p.SetMetadata(SystemContextKey, "You are helpful")
+	p.SetMetadata(MaxTokensKey, 500)
+	p.SetMetadata(TemperatureKey, 1.0)
+	p.SetMetadata("seed", int64(42))
+	p.SetMetadata("ratio", float32(0.5))
+	p.SetMetadata("tags", []string{"a", "b"})
+	p.SetMetadata("nothing", nil)
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var restored Prompt
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(restored.Sections, p.Sections) {
+		t.Errorf("Expected sections %+v, got %+v", p.Sections, restored.Sections)
+	}
+	if restored.String() != p.String() {
+		t.Errorf("Expected rendering to survive the round trip, got:\n%s", restored.String())
+	}
+
+	expected := map[string]any{
+		"model_high_quality": true,
+		SystemContextKey:     "You are helpful",
+		MaxTokensKey:         500,
+		TemperatureKey:       1.0,
+		"seed":               int64(42),
+		"ratio":              float32(0.5),
+		"tags":               []any{"a", "b"},
+		"nothing":            nil,
+	}
+	if !reflect.DeepEqual(restored.GetAllMetadata(), expected) {
+		t.Errorf("Expected metadata %#v, got %#v", expected, restored.GetAllMetadata())
+	}
+}
+
+func TestPromptMarshalJSONSchema(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{Intro: "Task", Instructions: []Instruction{"Do it"}})
+	p.SetMetadata(ModelKey, "m")
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := `{"version":1,"sections":[{"intro":"Task","instructions":["Do it"]}],"metadata":{"model":"m"}}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+
+	empty, err := json.Marshal(NewPrompt())
+	if err != nil || string(empty) != `{"version":1,"sections":[]}` {
+		t.Errorf("Unexpected empty prompt encoding %s (err %v)", empty, err)
+	}
+}
+
+func TestPromptUnmarshalJSONErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		contains string
+	}{
+		{name: "newer version", input: `{"version":99,"sections":[]}`, contains: "newer than supported"},
+		{name: "wrong type", input: `{"version":"1"}`, contains: "failed to unmarshal prompt"},
+		{name: "overflowing type", input: `{"version":1,"metadata":{"n":300},"metadata_types":{"n":"int8"}}`, contains: `metadata "n"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Prompt
+			err := json.Unmarshal([]byte(tt.input), &p)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Errorf("Expected error containing %q, got %v", tt.contains, err)
+			}
+		})
+	}
+}
+
+func TestPromptUnmarshalLegacyJSON(t *testing.T) {
+	legacy := `{"Sections":[{"Intro":"Task","Instructions":["Do it"],"DataBlocks":[{"Label":"Input","Content":"{}","Type":"json"}]}]}`
+
+	var p Prompt
+	if err := json.Unmarshal([]byte(legacy), &p); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Section{{
+		Intro:        "Task",
+		Instructions: []Instruction{"Do it"},
+		DataBlocks:   []DataBlock{{Label: "Input", Content: "{}", Type: "json"}},
+	}}
+	if !reflect.DeepEqual(p.Sections, expected) {
+		t.Errorf("Expected sections %+v, got %+v", expected, p.Sections)
+	}
+
+	var section Section
+	if err := json.Unmarshal([]byte(`{"Intro":"Task","DataBlocks":[{"Content":"x","Type":"xml"}]}`), &section); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(section.DataBlocks) != 1 || section.DataBlocks[0].Content != "x" {
+		t.Errorf("Expected the legacy data blocks, got %+v", section)
+	}
+
+	data, err := json.Marshal(expected[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	section = Section{}
+	if err := json.Unmarshal(data, &section); err != nil || !reflect.DeepEqual(section, expected[0]) {
+		t.Errorf("Expected %+v to round trip, got %+v (err %v)", expected[0], section, err)
+	}
+}
+
+func TestPromptMarshalJSONUnsupportedMetadata(t *testing.T) {
+	p := NewPrompt()
+	p.SetMetadata("callback", func() {})
+
+	if _, err := json.Marshal(p); err == nil {
+		t.Error("Expected error for metadata that cannot be encoded")
+	}
+}
+
+func TestPromptMarshalJSONByValue(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{Intro: "Task", Instructions: []Instruction{"Say hello"}})
+	p.SetMetadata(ModelKey, "gpt-4")
+
+	expected, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	type job struct {
+		Prompt Prompt `json:"prompt"`
+	}
+	for name, v := range map[string]any{
+		"struct field": job{Prompt: *p},
+		"map value":    map[string]Prompt{"prompt": *p},
+	} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if want := `{"prompt":` + string(expected) + `}`; string(data) != want {
+			t.Errorf("%s: expected %s, got %s", name, want, data)
+		}
+	}
+
+	var decoded job
+	if err := json.Unmarshal([]byte(`{"prompt":`+string(expected)+`}`), &decoded); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if decoded.Prompt.GetMetadataString(ModelKey) != "gpt-4" {
+		t.Errorf("Expected metadata to survive, got %v", decoded.Prompt.GetAllMetadata())
+	}
+
+	var nilPrompt *Prompt
+	if data, err := json.Marshal(nilPrompt); err != nil || string(data) != "null" {
+		t.Errorf("Expected null, got %s (err %v)", data, err)
+	}
+}
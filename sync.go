@@ -0,0 +1,135 @@
+package prompt
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// SyncPrompt is a Prompt that can be built from multiple goroutines. All
+// methods are safe for concurrent use: writes are serialized and reads see
+// either all or nothing of every write. Readers that need a consistent view
+// of several values, or want to pass the prompt on to code expecting a
+// *Prompt, take a Snapshot.
+//
+// The wrapped prompt must not be accessed directly once it is handed to
+// NewSyncPrompt.
+type SyncPrompt struct {
+	mu sync.RWMutex
+	p  *Prompt
+}
+
+// NewSyncPrompt wraps p for concurrent use. A nil p starts an empty prompt.
+func NewSyncPrompt(p *Prompt) *SyncPrompt {
+	if p == nil {
+		p = NewPrompt()
+	}
+	return &SyncPrompt{p: p}
+}
+
+// AddSection appends a section and returns its index, which can be passed to
+// AddInstruction and AddDataBlock
+func (sp *SyncPrompt) AddSection(section Section) int {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.p.AddSection(section.Clone())
+	return len(sp.p.Sections) - 1
+}
+
+func (sp *SyncPrompt) AddSections(sections []Section) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	for _, section := range sections {
+		sp.p.AddSection(section.Clone())
+	}
+}
+
+// AddInstruction appends an instruction to the section at index
+func (sp *SyncPrompt) AddInstruction(index int, instruction Instruction) error {
+	return sp.updateSection(index, func(s *Section) {
+		s.AddInstruction(instruction)
+	})
+}
+
+// AddDataBlock appends a data block to the section at index
+func (sp *SyncPrompt) AddDataBlock(index int, block DataBlock) error {
+	return sp.updateSection(index, func(s *Section) {
+		s.DataBlocks = append(s.DataBlocks, block)
+	})
+}
+
+func (sp *SyncPrompt) updateSection(index int, fn func(s *Section)) error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if index < 0 || index >= len(sp.p.Sections) {
+		return fmt.Errorf("section index %d out of range [0, %d)", index, len(sp.p.Sections))
+	}
+	fn(&sp.p.Sections[index])
+	return nil
+}
+
+// Update calls fn with the wrapped prompt while holding the write lock. fn
+// must not keep references to the prompt or its sections after it returns.
+func (sp *SyncPrompt) Update(fn func(p *Prompt)) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	fn(sp.p)
+}
+
+func (sp *SyncPrompt) SetMetadata(key string, value any) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.p.SetMetadata(key, value)
+}
+
+func (sp *SyncPrompt) GetMetadata(key string) (any, bool) {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return sp.p.GetMetadata(key)
+}
+
+func (sp *SyncPrompt) DeleteMetadata(key string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.p.DeleteMetadata(key)
+}
+
+func (sp *SyncPrompt) SetTokenizer(t Tokenizer) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.p.SetTokenizer(t)
+}
+
+// Snapshot returns a Clone of the prompt that later writes to sp do not
+// affect
+func (sp *SyncPrompt) Snapshot() *Prompt {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return sp.p.Clone()
+}
+
+func (sp *SyncPrompt) String() string {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return sp.p.String()
+}
+
+// WriteTo streams the prompt to w. Writers are blocked until it returns, so
+// prefer writing a Snapshot to slow destinations.
+func (sp *SyncPrompt) WriteTo(w io.Writer) (int64, error) {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return sp.p.WriteTo(w)
+}
+
+func (sp *SyncPrompt) TokenCount() int {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return sp.p.TokenCount()
+}
+
+func (sp *SyncPrompt) WordCount() int {
+	sp.mu.RLock()
+	defer sp.mu.RUnlock()
+	return sp.p.WordCount()
+}
@@ -0,0 +1,97 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestSyncPromptConcurrentBuild fills one prompt from several goroutines
+// while others read it. Run with -race.
+func TestSyncPromptConcurrentBuild(t *testing.T) {
+	sp := NewSyncPrompt(nil)
+	docs := sp.AddSection(NewSection("Documents"))
+
+	const workers, perWorker = 8, 25
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				block := DataBlock{Label: fmt.Sprintf("Doc %d-%d", w, i), Content: "{}", Type: "json"}
+				if err := sp.AddDataBlock(docs, block); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				sp.SetMetadata(fmt.Sprintf("stage_%d", w), i)
+				_, _ = sp.GetMetadata(ModelKey)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				_ = sp.String()
+				_ = sp.TokenCount()
+				snapshot := sp.Snapshot()
+				snapshot.AddSection(NewSection("local only"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	p := sp.Snapshot()
+	if len(p.Sections) != 1 {
+		t.Fatalf("Expected 1 section, got %d", len(p.Sections))
+	}
+	if got := len(p.Sections[0].DataBlocks); got != workers*perWorker {
+		t.Errorf("Expected %d data blocks, got %d", workers*perWorker, got)
+	}
+	for w := 0; w < workers; w++ {
+		if got := p.GetMetadataInt(fmt.Sprintf("stage_%d", w)); got != perWorker-1 {
+			t.Errorf("Expected stage_%d to be %d, got %d", w, perWorker-1, got)
+		}
+	}
+}
+
+func TestSyncPromptSnapshotIsIndependent(t *testing.T) {
+	sp := NewSyncPrompt(nil)
+	i := sp.AddSection(Section{Intro: "Task", Instructions: make([]Instruction, 0, 4)})
+	sp.SetMetadata(ModelKey, "a")
+
+	snapshot := sp.Snapshot()
+
+	if err := sp.AddInstruction(i, "added later"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	sp.SetMetadata(ModelKey, "b")
+	snapshot.Sections[0].AddInstruction("snapshot only")
+
+	if got := snapshot.GetMetadataString(ModelKey); got != "a" {
+		t.Errorf("Expected snapshot model 'a', got %q", got)
+	}
+	if strings.Contains(sp.String(), "snapshot only") {
+		t.Errorf("Expected snapshot changes not to leak into the shared prompt, got %q", sp.String())
+	}
+	if strings.Contains(snapshot.String(), "added later") {
+		t.Errorf("Expected later writes not to show up in the snapshot, got %q", snapshot.String())
+	}
+}
+
+func TestSyncPromptSectionIndexOutOfRange(t *testing.T) {
+	sp := NewSyncPrompt(nil)
+
+	if err := sp.AddInstruction(0, "x"); err == nil {
+		t.Error("Expected error for missing section")
+	}
+	if err := sp.AddDataBlock(-1, DataBlock{}); err == nil {
+		t.Error("Expected error for negative index")
+	}
+}
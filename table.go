@@ -0,0 +1,346 @@
+package prompt
+
+import (
+	"encoding/csv"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TableFormat selects how AddTable lays out rows
+type TableFormat int
+
+const (
+	// TableMarkdown renders a GitHub-flavored Markdown table (type "markdown")
+	TableMarkdown TableFormat = iota
+	// TableCSV renders comma-separated values as written by encoding/csv
+	// (type "csv")
+	TableCSV
+	// TableTSV renders tab-separated values; tabs, newlines, carriage returns
+	// and backslashes in cells are written as \t, \n, \r and \\ (type "tsv")
+	TableTSV
+)
+
+func (f TableFormat) String() string {
+	switch f {
+	case TableMarkdown:
+		return "markdown"
+	case TableCSV:
+		return "csv"
+	case TableTSV:
+		return "tsv"
+	}
+	return fmt.Sprintf("TableFormat(%d)", int(f))
+}
+
+// TableOptions configures AddTable. The zero value renders all columns of
+// all rows as a Markdown table.
+type TableOptions struct {
+	Format TableFormat
+	// Columns selects and orders the columns by header name. Empty means all
+	// columns in their default order.
+	Columns []string
+	// MaxRows caps the number of rows; the rest are summarized as
+	// "+N more rows". Markdown tables get the note below the table, CSV and TSV
+	// blocks in the label, so their content stays valid. 0 means no limit.
+	MaxRows int
+}
+
+// AddTable adds rows as a tabular data block, which takes far fewer tokens
+// than the same data as JSON. rows must be a slice or array of structs,
+// pointers to structs or maps with string keys.
+//
+// Struct columns are the exported fields in declaration order, named after
+// the field. A `table` struct tag renames or hides a field and can move it:
+//
+//	type Order struct {
+//		ID    int     `table:"Order"`
+//		Total float64 `table:",order=1"` // after all columns without order
+//		Notes string  `table:"-"`
+//	}
+//
+// Columns are sorted by their order (default 0), keeping declaration order
+// for ties. Map columns are the keys of all rows, sorted. Nil pointers and
+// missing map keys render as empty cells.
+func (s *Section) AddTable(label string, rows any, opts TableOptions) error {
+	headers, cells, err := tableCells(rows)
+	if err != nil {
+		return fmt.Errorf("failed to build table: %w", err)
+	}
+
+	if len(opts.Columns) > 0 {
+		index := make(map[string]int, len(headers))
+		for i, h := range headers {
+			index[h] = i
+		}
+		picked := make([]int, len(opts.Columns))
+		for i, name := range opts.Columns {
+			j, ok := index[name]
+			if !ok {
+				return fmt.Errorf("failed to build table: unknown column %q", name)
+			}
+			picked[i] = j
+		}
+		headers = opts.Columns
+		for r, row := range cells {
+			selected := make([]string, len(picked))
+			for i, j := range picked {
+				selected[i] = row[j]
+			}
+			cells[r] = selected
+		}
+	}
+
+	if len(headers) == 0 {
+		return fmt.Errorf("failed to build table: no columns")
+	}
+
+	var more int
+	if opts.MaxRows > 0 && len(cells) > opts.MaxRows {
+		more = len(cells) - opts.MaxRows
+		cells = cells[:opts.MaxRows]
+	}
+
+	var content string
+	switch opts.Format {
+	case TableMarkdown:
+		content = markdownTable(headers, cells)
+	case TableCSV:
+		content, err = csvTable(headers, cells)
+		if err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	case TableTSV:
+		content = tsvTable(headers, cells)
+	default:
+		return fmt.Errorf("failed to build table: unsupported format %v", opts.Format)
+	}
+
+	if more > 0 {
+		note := fmt.Sprintf("+%d more rows", more)
+		if more == 1 {
+			note = "+1 more row"
+		}
+		if opts.Format == TableMarkdown {
+			// the blank line keeps Markdown from reading the note as a table row
+			content += "\n\n" + note
+		} else if label != "" {
+			label += " (" + note + ")"
+		} else {
+			label = note
+		}
+	}
+
+	s.DataBlocks = append(s.DataBlocks, DataBlock{
+		Label:   label,
+		Content: content,
+		Type:    opts.Format.String(),
+	})
+	return nil
+}
+
+// tableColumn is a struct field shown as a column
+type tableColumn struct {
+	name  string
+	index []int
+	order int
+}
+
+// tableCells reflects over rows and returns the headers and the formatted
+// cells of every row
+func tableCells(rows any) ([]string, [][]string, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, nil, fmt.Errorf("rows must be a slice or array, got %T", rows)
+	}
+
+	elem := v.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+
+	switch {
+	case elem.Kind() == reflect.Struct:
+		columns, err := structColumns(elem)
+		if err != nil {
+			return nil, nil, err
+		}
+		headers := make([]string, len(columns))
+		for i, c := range columns {
+			headers[i] = c.name
+		}
+		cells := make([][]string, v.Len())
+		for r := range cells {
+			row := indirect(v.Index(r))
+			cells[r] = make([]string, len(columns))
+			if !row.IsValid() {
+				continue
+			}
+			for i, c := range columns {
+				if field, err := row.FieldByIndexErr(c.index); err == nil {
+					cells[r][i] = formatCell(field)
+				}
+			}
+		}
+		return headers, cells, nil
+
+	case elem.Kind() == reflect.Map && elem.Key().Kind() == reflect.String,
+		elem.Kind() == reflect.Interface:
+		return mapCells(v)
+	}
+
+	return nil, nil, fmt.Errorf("rows must contain structs or maps with string keys, got %T", rows)
+}
+
+// structColumns returns the exported fields of t as columns, including
+// fields promoted from embedded structs
+func structColumns(t reflect.Type) ([]tableColumn, error) {
+	var columns []tableColumn
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || (f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct) {
+			continue
+		}
+
+		c := tableColumn{name: f.Name, index: f.Index}
+		if tag, ok := f.Tag.Lookup("table"); ok {
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+			if name != "" {
+				c.name = name
+			}
+			for _, option := range strings.Split(options, ",") {
+				value, ok := strings.CutPrefix(option, "order=")
+				if !ok {
+					continue
+				}
+				order, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("field %s: invalid order %q", f.Name, value)
+				}
+				c.order = order
+			}
+		}
+		columns = append(columns, c)
+	}
+
+	sort.SliceStable(columns, func(i, j int) bool { return columns[i].order < columns[j].order })
+	return columns, nil
+}
+
+// mapCells returns the cells of a slice of maps; the columns are the keys of
+// all rows, sorted
+func mapCells(v reflect.Value) ([]string, [][]string, error) {
+	rows := make([]reflect.Value, v.Len())
+	keys := make(map[string]bool)
+	for r := range rows {
+		row := indirect(v.Index(r))
+		if row.IsValid() && (row.Kind() != reflect.Map || row.Type().Key().Kind() != reflect.String) {
+			return nil, nil, fmt.Errorf("row %d: expected a map with string keys, got %s", r, row.Type())
+		}
+		rows[r] = row
+		if row.IsValid() {
+			for _, key := range row.MapKeys() {
+				keys[key.String()] = true
+			}
+		}
+	}
+
+	headers := make([]string, 0, len(keys))
+	for key := range keys {
+		headers = append(headers, key)
+	}
+	sort.Strings(headers)
+
+	cells := make([][]string, len(rows))
+	for r, row := range rows {
+		cells[r] = make([]string, len(headers))
+		if !row.IsValid() {
+			continue
+		}
+		for i, h := range headers {
+			value := row.MapIndex(reflect.ValueOf(h).Convert(row.Type().Key()))
+			if value.IsValid() {
+				cells[r][i] = formatCell(value)
+			}
+		}
+	}
+	return headers, cells, nil
+}
+
+// formatCell formats a value with fmt, rendering nil as an empty cell
+func formatCell(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// indirect dereferences pointers and interfaces, returning the zero Value
+// for nil
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+var markdownCellEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+func markdownTable(headers []string, cells [][]string) string {
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for _, cell := range row {
+			b.WriteString(" " + markdownCellEscaper.Replace(cell) + " |")
+		}
+	}
+
+	writeRow(headers)
+	b.WriteString("\n|" + strings.Repeat(" --- |", len(headers)))
+	for _, row := range cells {
+		b.WriteString("\n")
+		writeRow(row)
+	}
+	return b.String()
+}
+
+func csvTable(headers []string, cells [][]string) (string, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(headers); err != nil {
+		return "", err
+	}
+	if err := w.WriteAll(cells); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+var tsvCellEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func tsvTable(headers []string, cells [][]string) string {
+	lines := make([]string, 0, len(cells)+1)
+	for _, row := range append([][]string{headers}, cells...) {
+		escaped := make([]string, len(row))
+		for i, cell := range row {
+			escaped[i] = tsvCellEscaper.Replace(cell)
+		}
+		lines = append(lines, strings.Join(escaped, "\t"))
+	}
+	return strings.Join(lines, "\n")
+}
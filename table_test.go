@@ -0,0 +1,193 @@
+package prompt
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+)
+
+type tableTestAddress struct {
+	City string
+}
+
+type tableTestOrder struct {
+	ID       int     `table:"Order"`
+	Total    float64 `table:",order=1"`
+	Customer string
+	Notes    string `table:"-"`
+	Discount *int
+	tableTestAddress
+	internal string
+}
+
+func newTableTestOrders() []tableTestOrder {
+	ten := 10
+	return []tableTestOrder{
+		{ID: 1, Total: 9.5, Customer: "Ann | Co", Notes: "secret", Discount: &ten, tableTestAddress: tableTestAddress{City: "Berlin"}},
+		{ID: 2, Total: 20, Customer: "Bob\nSmith", tableTestAddress: tableTestAddress{City: "Paris"}},
+		{ID: 3, Total: 7.25, Customer: "Cid", tableTestAddress: tableTestAddress{City: "Rome"}},
+	}
+}
+
+func TestAddTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     any
+		opts     TableOptions
+		expected string
+		typ      string
+		label    string // defaults to "Orders"
+	}{
+		{
+			name: "markdown",
+			rows: newTableTestOrders(),
+			expected: "| Order | Customer | Discount | City | Total |\n" +
+				"| --- | --- | --- | --- | --- |\n" +
+				"| 1 | Ann \\| Co | 10 | Berlin | 9.5 |\n" +
+				"| 2 | Bob<br>Smith |  | Paris | 20 |\n" +
+				"| 3 | Cid |  | Rome | 7.25 |",
+			typ: "markdown",
+		},
+		{
+			name:     "csv with columns",
+			rows:     newTableTestOrders(),
+			opts:     TableOptions{Format: TableCSV, Columns: []string{"Customer", "Order"}},
+			expected: "Customer,Order\nAnn | Co,1\n\"Bob\nSmith\",2\nCid,3",
+			typ:      "csv",
+		},
+		{
+			name:     "tsv",
+			rows:     []map[string]any{{"b": "x\ty", "a": 1}, {"a": 2, "c": true}},
+			opts:     TableOptions{Format: TableTSV},
+			expected: "a\tb\tc\n1\tx\\ty\t\n2\t\ttrue",
+			typ:      "tsv",
+		},
+		{
+			name:     "row cap",
+			rows:     newTableTestOrders(),
+			opts:     TableOptions{Columns: []string{"Order"}, MaxRows: 1},
+			expected: "| Order |\n| --- |\n| 1 |\n\n+2 more rows",
+			typ:      "markdown",
+		},
+		{
+			name:     "row cap of one",
+			rows:     []*tableTestOrder{{ID: 1}, nil},
+			opts:     TableOptions{Format: TableCSV, Columns: []string{"Order"}, MaxRows: 1},
+			expected: "Order\n1",
+			typ:      "csv",
+			label:    "Orders (+1 more row)",
+		},
+		{
+			name:     "tsv row cap",
+			rows:     newTableTestOrders(),
+			opts:     TableOptions{Format: TableTSV, Columns: []string{"Order"}, MaxRows: 2},
+			expected: "Order\n1\n2",
+			typ:      "tsv",
+			label:    "Orders (+1 more row)",
+		},
+		{
+			name:     "nil rows",
+			rows:     []*tableTestOrder{nil},
+			opts:     TableOptions{Columns: []string{"Order", "City"}},
+			expected: "| Order | City |\n| --- | --- |\n|  |  |",
+			typ:      "markdown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			section := NewSection("Data")
+			if err := section.AddTable("Orders", tt.rows, tt.opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			block := section.DataBlocks[0]
+			if block.Content != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, block.Content)
+			}
+			label := tt.label
+			if label == "" {
+				label = "Orders"
+			}
+			if block.Type != tt.typ || block.Label != label {
+				t.Errorf("Unexpected data block %+v", block)
+			}
+		})
+	}
+}
+
+func TestAddTableErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rows any
+		opts TableOptions
+	}{
+		{name: "not a slice", rows: tableTestOrder{}},
+		{name: "slice of strings", rows: []string{"a"}},
+		{name: "unknown column", rows: newTableTestOrders(), opts: TableOptions{Columns: []string{"Notes"}}},
+		{name: "no columns", rows: []map[string]any{}},
+		{name: "mixed rows", rows: []any{map[string]any{"a": 1}, 5}},
+		{name: "unknown format", rows: newTableTestOrders(), opts: TableOptions{Format: TableFormat(9)}},
+		{name: "invalid order", rows: []struct {
+			A int `table:",order=x"`
+		}{{1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			section := NewSection("Data")
+			if err := section.AddTable("Orders", tt.rows, tt.opts); err == nil {
+				t.Error("Expected error")
+			}
+			if len(section.DataBlocks) != 0 {
+				t.Errorf("Expected no data block, got %+v", section.DataBlocks)
+			}
+		})
+	}
+}
+
+func TestAddTableRender(t *testing.T) {
+	p, err := Build().
+		Section("Orders").
+		Instruct("Find the largest order").
+		Table("Orders", newTableTestOrders(), TableOptions{Columns: []string{"Order", "Total"}}).
+		Done()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "\nOrders:\n- Find the largest order\n\nOrders:\n```markdown\n| Order | Total |\n| --- | --- |\n| 1 | 9.5 |\n| 2 | 20 |\n| 3 | 7.25 |\n```\n---"
+	if p.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, p.String())
+	}
+
+	parsed, err := Parse(p.String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if parsed.Sections[0].DataBlocks[0] != p.Sections[0].DataBlocks[0] {
+		t.Errorf("Expected table to survive parsing, got %+v", parsed.Sections[0].DataBlocks[0])
+	}
+
+	if _, err := Build().Section("Orders").Table("Orders", 5, TableOptions{}).Done(); err == nil || !strings.Contains(err.Error(), `section "Orders"`) {
+		t.Errorf("Expected builder error, got %v", err)
+	}
+}
+
+func TestAddTableCSVStaysValid(t *testing.T) {
+	section := NewSection("Data")
+	if err := section.AddTable("", newTableTestOrders(), TableOptions{Format: TableCSV, MaxRows: 2}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	block := section.DataBlocks[0]
+	if block.Label != "+1 more row" {
+		t.Errorf("Expected the note as label, got %q", block.Label)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(block.Content)).ReadAll()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 3 || records[2][1] != "Bob\nSmith" {
+		t.Errorf("Expected header and 2 rows, got %q", records)
+	}
+}
@@ -0,0 +1,322 @@
+package prompt
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Template is a reusable prompt whose intros, instructions, data block labels
+// and data block content may contain text/template placeholders like {{.Name}}.
+// Sections, priorities and metadata are copied to every executed prompt.
+type Template struct {
+	base     *Prompt // snapshot taken by NewTemplate
+	sections []sectionTemplate
+	vars     []string
+}
+
+type sectionTemplate struct {
+	intro        *template.Template
+	instructions []*template.Template
+	labels       []*template.Template
+	contents     []*template.Template
+}
+
+// VarsError reports variables a template references but were not supplied
+// (Missing) and supplied variables the template never uses (Unused)
+type VarsError struct {
+	Missing []string
+	Unused  []string
+}
+
+func (e *VarsError) Error() string {
+	var parts []string
+	if len(e.Missing) > 0 {
+		parts = append(parts, "missing variables: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unused) > 0 {
+		parts = append(parts, "unused variables: "+strings.Join(e.Unused, ", "))
+	}
+	return "template " + strings.Join(parts, "; ")
+}
+
+// NewTemplate parses the placeholders in every text of p. The template works
+// on a snapshot of p, so later changes to p do not affect it, and p is not
+// modified.
+func NewTemplate(p *Prompt) (*Template, error) {
+	p = p.Clone()
+	t := &Template{
+		base:     p,
+		sections: make([]sectionTemplate, len(p.Sections)),
+	}
+	vars := make(map[string]bool)
+
+	parseText := func(name, text string) (*template.Template, error) {
+		if !strings.Contains(text, "{{") {
+			return nil, nil
+		}
+		tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template: %w", err)
+		}
+		c := varCollector{tmpl: tmpl, vars: vars, seen: make(map[string]bool)}
+		c.collect(tmpl.Tree.Root, true)
+		if c.undefined != "" {
+			return nil, fmt.Errorf("failed to parse template: %s: no such template %q", name, c.undefined)
+		}
+		return tmpl, nil
+	}
+
+	for i, section := range p.Sections {
+		st := &t.sections[i]
+		prefix := fmt.Sprintf("section %d", i+1)
+
+		var err error
+		if st.intro, err = parseText(prefix+" intro", section.Intro); err != nil {
+			return nil, err
+		}
+
+		st.instructions = make([]*template.Template, len(section.Instructions))
+		for j, instruction := range section.Instructions {
+			name := fmt.Sprintf("%s instruction %d", prefix, j+1)
+			if st.instructions[j], err = parseText(name, string(instruction)); err != nil {
+				return nil, err
+			}
+		}
+
+		st.labels = make([]*template.Template, len(section.DataBlocks))
+		st.contents = make([]*template.Template, len(section.DataBlocks))
+		for j, block := range section.DataBlocks {
+			name := fmt.Sprintf("%s data block %d", prefix, j+1)
+			if st.labels[j], err = parseText(name+" label", block.Label); err != nil {
+				return nil, err
+			}
+			if st.contents[j], err = parseText(name+" content", block.Content); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for name := range vars {
+		t.vars = append(t.vars, name)
+	}
+	sort.Strings(t.vars)
+
+	return t, nil
+}
+
+// Vars returns the sorted names of all variables the template references
+func (t *Template) Vars() []string {
+	return append([]string(nil), t.vars...)
+}
+
+// Execute fills in the placeholders and returns a new prompt. vars is a
+// map[string]T or a struct (or pointer to struct) whose exported fields are
+// the variables. Missing variables fail with a *VarsError. When the only
+// problem is unused variables, the prompt is returned together with a
+// *VarsError listing them, so callers can decide whether to treat it as fatal.
+func (t *Template) Execute(vars any) (*Prompt, error) {
+	supplied, err := suppliedVars(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	var varsErr VarsError
+	for _, name := range t.vars {
+		if !supplied[name] {
+			varsErr.Missing = append(varsErr.Missing, name)
+		}
+	}
+	for name := range supplied {
+		if !t.referenced(name) {
+			varsErr.Unused = append(varsErr.Unused, name)
+		}
+	}
+	sort.Strings(varsErr.Unused)
+	if len(varsErr.Missing) > 0 {
+		return nil, &varsErr
+	}
+
+	p := NewPrompt()
+	p.metadata = t.base.GetAllMetadata()
+	p.tokenizer = t.base.tokenizer
+	p.Sections = make([]Section, len(t.base.Sections))
+
+	for i, section := range t.base.Sections {
+		st := t.sections[i]
+		out := section
+		out.Instructions = make([]Instruction, len(section.Instructions))
+		out.DataBlocks = make([]DataBlock, len(section.DataBlocks))
+
+		if out.Intro, err = executeText(st.intro, section.Intro, vars); err != nil {
+			return nil, err
+		}
+		for j, instruction := range section.Instructions {
+			text, err := executeText(st.instructions[j], string(instruction), vars)
+			if err != nil {
+				return nil, err
+			}
+			out.Instructions[j] = Instruction(text)
+		}
+		for j, block := range section.DataBlocks {
+			out.DataBlocks[j] = block
+			if out.DataBlocks[j].Label, err = executeText(st.labels[j], block.Label, vars); err != nil {
+				return nil, err
+			}
+			if out.DataBlocks[j].Content, err = executeText(st.contents[j], block.Content, vars); err != nil {
+				return nil, err
+			}
+		}
+
+		p.Sections[i] = out
+	}
+
+	if len(varsErr.Unused) > 0 {
+		return p, &varsErr
+	}
+	return p, nil
+}
+
+func (t *Template) referenced(name string) bool {
+	i := sort.SearchStrings(t.vars, name)
+	return i < len(t.vars) && t.vars[i] == name
+}
+
+func executeText(tmpl *template.Template, text string, vars any) (string, error) {
+	if tmpl == nil {
+		return text, nil
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// suppliedVars returns the variable names available in vars
+func suppliedVars(vars any) (map[string]bool, error) {
+	names := make(map[string]bool)
+
+	v := reflect.ValueOf(vars)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return names, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Invalid:
+		return names, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("template variables must be a map with string keys, got %s", v.Type())
+		}
+		for _, key := range v.MapKeys() {
+			names[key.String()] = true
+		}
+	case reflect.Struct:
+		for _, field := range reflect.VisibleFields(v.Type()) {
+			if field.IsExported() && !field.Anonymous {
+				names[field.Name] = true
+			}
+		}
+	default:
+		return nil, fmt.Errorf("template variables must be a map or struct, got %s", v.Type())
+	}
+
+	return names, nil
+}
+
+// varCollector records the top-level variables a template references
+type varCollector struct {
+	tmpl      *template.Template // resolves {{template "name"}} calls
+	vars      map[string]bool
+	seen      map[string]bool // defined templates already walked
+	undefined string          // first called template that is not defined
+}
+
+// collect records the variables referenced below node. Fields accessed inside
+// {{range}} and {{with}} bodies are relative to a different dot and therefore
+// only counted when written as $.Name. Templates called with {{template}} or
+// {{block}} are walked when they get the top-level dot.
+func (c *varCollector) collect(node parse.Node, rootDot bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			c.collect(child, rootDot)
+		}
+	case *parse.ActionNode:
+		c.collect(n.Pipe, rootDot)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			c.collect(cmd, rootDot)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			c.collect(arg, rootDot)
+		}
+	case *parse.FieldNode:
+		if rootDot && len(n.Ident) > 0 {
+			c.vars[n.Ident[0]] = true
+		}
+	case *parse.ChainNode:
+		c.collect(n.Node, rootDot)
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			c.vars[n.Ident[1]] = true
+		}
+	case *parse.IfNode:
+		c.collect(n.Pipe, rootDot)
+		c.collect(n.List, rootDot)
+		c.collect(n.ElseList, rootDot)
+	case *parse.RangeNode:
+		c.collect(n.Pipe, rootDot)
+		c.collect(n.List, false)
+		c.collect(n.ElseList, rootDot)
+	case *parse.WithNode:
+		c.collect(n.Pipe, rootDot)
+		c.collect(n.List, false)
+		c.collect(n.ElseList, rootDot)
+	case *parse.TemplateNode:
+		c.collect(n.Pipe, rootDot)
+		called := c.tmpl.Lookup(n.Name)
+		if called == nil || called.Tree == nil {
+			if c.undefined == "" {
+				c.undefined = n.Name
+			}
+			return
+		}
+		// the called template sees the pipeline's value as both . and $, so
+		// only a call with the top-level dot refers to the variables
+		if c.seen[n.Name] || !passesRoot(n.Pipe, rootDot) {
+			return
+		}
+		c.seen[n.Name] = true
+		c.collect(called.Tree.Root, true)
+	}
+}
+
+// passesRoot reports whether pipe evaluates to the top-level dot: "." where
+// dot is the top level, or "$"
+func passesRoot(pipe *parse.PipeNode, rootDot bool) bool {
+	if pipe == nil || len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.DotNode:
+		return rootDot
+	case *parse.VariableNode:
+		return len(arg.Ident) == 1 && arg.Ident[0] == "$"
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package prompt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTemplateExecute(t *testing.T) {
+	base := NewPrompt()
+	task := NewSection("Write a {{.Kind}} about {{.Topic}}")
+	task.AddInstruction("Target audience: {{.Audience}}")
+	task.AddInstruction("Keep it under 200 words")
+	task.AddRawJSON("{{.Topic}} facts", `{"topic": "{{.Topic}}"}`)
+	task.Required = true
+	base.AddSection(task)
+	base.SetMetadata("model", "test-model")
+
+	tmpl, err := NewTemplate(base)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(tmpl.Vars(), []string{"Audience", "Kind", "Topic"}) {
+		t.Errorf("Unexpected vars %v", tmpl.Vars())
+	}
+
+	p, err := tmpl.Execute(map[string]any{"Kind": "blog post", "Topic": "Go", "Audience": "beginners"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "\nWrite a blog post about Go:\n- Target audience: beginners\n- Keep it under 200 words\n\n" +
+		"Go facts:\n```json\n{\"topic\": \"Go\"}\n```\n---"
+	if p.String() != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, p.String())
+	}
+	if !p.Sections[0].Required || p.GetMetadataString("model") != "test-model" {
+		t.Error("Expected section flags and metadata to be copied")
+	}
+	if base.Sections[0].Intro != "Write a {{.Kind}} about {{.Topic}}" {
+		t.Error("Expected the template prompt to stay unchanged")
+	}
+}
+
+func TestTemplateExecuteStruct(t *testing.T) {
+	base := NewPrompt()
+	base.AddSection(Section{Intro: "Write a {{.Kind}} about {{.Topic}}", Instructions: []Instruction{"Target audience: {{.Audience}}"}})
+
+	tmpl, err := NewTemplate(base)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	type vars struct {
+		Kind, Topic, Audience string
+	}
+	p, err := tmpl.Execute(&vars{Kind: "poem", Topic: "Rust", Audience: "experts"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.Sections[0].Intro != "Write a poem about Rust" {
+		t.Errorf("Unexpected intro %q", p.Sections[0].Intro)
+	}
+}
+
+func TestTemplateMissingAndUnusedVars(t *testing.T) {
+	base := NewPrompt()
+	base.AddSection(Section{Intro: "Write a {{.Kind}} about {{.Topic}}", Instructions: []Instruction{"Target audience: {{.Audience}}"}})
+
+	tmpl, err := NewTemplate(base)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	p, err := tmpl.Execute(map[string]string{"Kind": "essay", "Tone": "formal"})
+	var varsErr *VarsError
+	if !errors.As(err, &varsErr) {
+		t.Fatalf("Expected *VarsError, got %v", err)
+	}
+	if p != nil {
+		t.Error("Expected no prompt when variables are missing")
+	}
+	if !reflect.DeepEqual(varsErr.Missing, []string{"Audience", "Topic"}) || !reflect.DeepEqual(varsErr.Unused, []string{"Tone"}) {
+		t.Errorf("Unexpected error %+v", varsErr)
+	}
+
+	p, err = tmpl.Execute(map[string]string{"Kind": "essay", "Topic": "Go", "Audience": "all", "Tone": "formal"})
+	if !errors.As(err, &varsErr) || len(varsErr.Missing) != 0 || !reflect.DeepEqual(varsErr.Unused, []string{"Tone"}) {
+		t.Fatalf("Expected unused variable error, got %v", err)
+	}
+	if p == nil || p.Sections[0].Intro != "Write a essay about Go" {
+		t.Error("Expected the prompt to be returned alongside unused variables")
+	}
+}
+
+func TestTemplateRangeAndWith(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{
+		Intro:        "Rules",
+		Instructions: []Instruction{"{{range .Rules}}{{.Text}} ({{$.Severity}}); {{end}}"},
+	})
+
+	tmpl, err := NewTemplate(p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(tmpl.Vars(), []string{"Rules", "Severity"}) {
+		t.Errorf("Expected range body fields to be ignored, got %v", tmpl.Vars())
+	}
+
+	type rule struct{ Text string }
+	out, err := tmpl.Execute(map[string]any{"Rules": []rule{{"a"}, {"b"}}, "Severity": "high"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.Sections[0].Instructions[0] != "a (high); b (high); " {
+		t.Errorf("Unexpected instruction %q", out.Sections[0].Instructions[0])
+	}
+}
+
+func TestTemplateDefineAndBlock(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{
+		Intro: `{{define "who"}}{{.Audience}}{{template "who2" $}}{{end}}{{define "who2"}} aged {{.Age}}{{end}}Write for {{template "who" .}}`,
+		Instructions: []Instruction{
+			`{{block "tone" .}}Tone: {{.Tone}}{{end}}`,
+			`{{define "item"}}{{.Name}}{{end}}{{range .Items}}{{template "item" .}} {{end}}`,
+		},
+	})
+
+	tmpl, err := NewTemplate(p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(tmpl.Vars(), []string{"Age", "Audience", "Items", "Tone"}) {
+		t.Errorf("Expected variables of called templates, got %v", tmpl.Vars())
+	}
+
+	_, err = tmpl.Execute(map[string]any{"Audience": "kids", "Items": []any{}, "Tone": "fun"})
+	var varsErr *VarsError
+	if !errors.As(err, &varsErr) || !reflect.DeepEqual(varsErr.Missing, []string{"Age"}) {
+		t.Fatalf("Expected *VarsError for Age, got %v", err)
+	}
+
+	type item struct{ Name string }
+	out, err := tmpl.Execute(map[string]any{"Audience": "kids", "Age": 8, "Items": []item{{"a"}, {"b"}}, "Tone": "fun"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if out.Sections[0].Intro != "Write for kids aged 8" || out.Sections[0].Instructions[1] != "a b " {
+		t.Errorf("Unexpected prompt %+v", out.Sections[0])
+	}
+
+	undefined := NewPrompt()
+	undefined.AddSection(Section{Intro: `{{template "missing" .}}`})
+	if _, err := NewTemplate(undefined); err == nil {
+		t.Error("Expected error for a call to an undefined template")
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	p := NewPrompt()
+	p.AddSection(Section{Intro: "{{.Broken"})
+	if _, err := NewTemplate(p); err == nil {
+		t.Error("Expected parse error")
+	}
+
+	p.Sections[0].Intro = "{{.Kind}}"
+	tmpl, err := NewTemplate(p)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := tmpl.Execute(42); err == nil {
+		t.Error("Expected error for non-map, non-struct variables")
+	}
+	if _, err := tmpl.Execute(map[int]string{1: "x"}); err == nil {
+		t.Error("Expected error for map without string keys")
+	}
+}
+
+func TestTemplateIgnoresLaterChangesToBase(t *testing.T) {
+	base := NewPrompt()
+	task := NewSection("Write a {{.Kind}} about {{.Topic}}")
+	task.AddInstruction("Target audience: {{.Audience}}")
+	task.AddInstruction("Keep it under 200 words")
+	task.AddRawJSON("{{.Topic}} facts", `{"topic": "{{.Topic}}"}`)
+	task.Required = true
+	base.AddSection(task)
+	base.SetMetadata("model", "test-model")
+
+	tmpl, err := NewTemplate(base)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	base.Sections[0].AddInstruction("Mention {{.Extra}}")
+	base.Sections[0].AddRawJSON("Extra", "{}")
+	base.Sections[0].Intro = "Changed"
+	base.AddSection(Section{Intro: "Added later"})
+	base.SetMetadata("model", "other-model")
+
+	p, err := tmpl.Execute(map[string]any{"Kind": "poem", "Topic": "Go", "Audience": "kids"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(p.Sections) != 1 || len(p.Sections[0].Instructions) != 2 || len(p.Sections[0].DataBlocks) != 1 {
+		t.Errorf("Expected the prompt as it was when the template was created, got %+v", p.Sections)
+	}
+	if p.Sections[0].Intro != "Write a poem about Go" || p.GetMetadataString("model") != "test-model" {
+		t.Errorf("Expected the original intro and metadata, got %q and %q", p.Sections[0].Intro, p.GetMetadataString("model"))
+	}
+}
@@ -0,0 +1,20 @@
+package prompt
+
+// Tokenizer counts the tokens a model sees for a piece of text
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// HeuristicTokenizer estimates tokens as 1.4 tokens per whitespace separated
+// word. It is used when no Tokenizer is configured.
+type HeuristicTokenizer struct{}
+
+func (HeuristicTokenizer) CountTokens(text string) int {
+	return int(float64(countWords(text)) * heuristicTokensPerWord)
+}
+
+// SetTokenizer sets the tokenizer used by TokenCount. A nil tokenizer restores
+// the word count heuristic.
+func (p *Prompt) SetTokenizer(t Tokenizer) {
+	p.tokenizer = t
+}